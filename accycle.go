@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// name of action alias performing full AC power cycle of the system or its chassis
+const acCycleAction = "accycle"
+
+// acCycle performs AC power cycle using PowerCycle reset type of the system if it is allowed
+// or PowerCycle reset type of the chassis containing the system otherwise
+func acCycle(c config, sys system, allowed []string) (actionResult, error) {
+	if contains(allowed, "PowerCycle") {
+		tracef(c, "AC power cycle: using system PowerCycle")
+		return performAction(c, sys, "PowerCycle")
+	}
+	url, err := systemChassisURL(c, sys)
+	if err != nil {
+		return actionResult{}, err
+	}
+	b, err := redfishGet(c, url)
+	if err != nil {
+		return actionResult{}, err
+	}
+	var ch chassis
+	if err := json.Unmarshal(b, &ch); err != nil {
+		return actionResult{}, fmt.Errorf("cannot parse chassis: %s", err)
+	}
+	vals, err := allowableValues(c, ch.Actions.ChassisReset)
+	if err != nil {
+		return actionResult{}, err
+	}
+	if ch.Actions.ChassisReset.Target == "" || !contains(vals, "PowerCycle") {
+		return actionResult{}, fmt.Errorf("%w - no AC power cycle available: neither system nor chassis reset action allows PowerCycle", errUnsupported)
+	}
+	target := hostURL(c, ch.Actions.ChassisReset.Target)
+	tracef(c, "AC power cycle: using chassis PowerCycle, action target: %s", target)
+	payload := map[string]interface{}{}
+	for k, v := range c.params {
+		payload[k] = v
+	}
+	payload["ResetType"] = "PowerCycle"
+	resp, body, err := redfishPost(c, target, payload)
+	if err != nil {
+		return actionResult{}, err
+	}
+	return newActionResult("PowerCycle", resp, body), nil
+}
+
+// systemChassisURL returns URL of the chassis containing the system
+// the only chassis of the chassis collection is used if the system does not link its chassis
+func systemChassisURL(c config, sys system) (string, error) {
+	if len(sys.Links.Chassis) > 0 {
+		return hostURL(c, sys.Links.Chassis[0].OdataID), nil
+	}
+	return getChassisURL(c)
+}
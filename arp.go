@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// resolveMAC returns IP address of BMC with specified MAC address found in local ARP table
+// only hosts in the same network which communicated recently with this machine are found
+func resolveMAC(mac string) (string, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return "", fmt.Errorf("invalid -mac address: %s", err)
+	}
+	neighbors, err := arpTable()
+	if err != nil {
+		return "", err
+	}
+	var ips []string
+	for ip, addr := range neighbors {
+		if strings.EqualFold(addr, hw.String()) {
+			ips = append(ips, ip)
+		}
+	}
+	switch len(ips) {
+	case 0:
+		return "", fmt.Errorf("MAC address %s not found in ARP table - ping the network or wait until the BMC communicates", hw)
+	case 1:
+		return ips[0], nil
+	}
+	return "", fmt.Errorf("MAC address %s found in ARP table with multiple addresses: %s", hw, strings.Join(ips, ", "))
+}
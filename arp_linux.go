@@ -0,0 +1,29 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// arpTable returns complete entries of kernel ARP table as MAC addresses indexed by IP address
+func arpTable() (map[string]string, error) {
+	b, err := ioutil.ReadFile("/proc/net/arp")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read ARP table: %s", err)
+	}
+	neighbors := map[string]string{}
+	lines := strings.Split(string(b), "\n")
+	for _, line := range lines[1:] {
+		// IP address, HW type, flags, HW address, mask, device
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[2] == "0x0" {
+			continue
+		}
+		neighbors[fields[0]] = fields[3]
+	}
+	return neighbors, nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "fmt"
+
+// arpTable is not supported on this platform
+func arpTable() (map[string]string, error) {
+	return nil, fmt.Errorf("-mac is supported only on Linux")
+}
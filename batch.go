@@ -0,0 +1,467 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// batchMode returns true if requested function is run for multiple hosts with -hosts, -group or -targets-stdin
+func batchMode(c config) bool {
+	return c.hostfile != "" || c.group != "" || c.tgtstdin
+}
+
+// readHosts returns hosts listed in the file, one per line, skipping empty lines and comments starting with #
+func readHosts(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read hosts file: %s", err)
+	}
+	defer f.Close()
+	var hosts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hosts = append(hosts, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read hosts file: %s", err)
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("hosts file %s does not contain any hosts", path)
+	}
+	return hosts, nil
+}
+
+// type progress describes batch progress indicator and serializes output and report of completed hosts
+// indicator is printed on the last line of the terminal only and erased before any other output
+type progress struct {
+	mu      sync.Mutex
+	w       io.Writer
+	enabled bool
+	total   int
+	done    int
+	failed  int
+	denied  int
+	report  *batchReport
+	metrics []hostMetrics
+	locks   *hostLocks
+}
+
+// type hostLocks holds mutex of every BMC processed in batch mode
+type hostLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks mutex of the host (compared case-insensitively) and returns function unlocking it
+func (l *hostLocks) lock(host string) func() {
+	key := strings.ToLower(host)
+	l.mu.Lock()
+	m, ok := l.locks[key]
+	if !ok {
+		m = &sync.Mutex{}
+		l.locks[key] = m
+	}
+	l.mu.Unlock()
+	m.Lock()
+	return m.Unlock
+}
+
+// clear erases progress indicator, must be called with mutex held
+func (p *progress) clear() {
+	if p.enabled {
+		fmt.Fprint(p.w, "\r\033[K")
+	}
+}
+
+// show prints progress indicator, must be called with mutex held
+func (p *progress) show() {
+	if p.enabled {
+		fmt.Fprintf(p.w, "\r\033[K%d/%d done, %d failed", p.done, p.total, p.failed)
+	}
+}
+
+// batch runs requested function for every host using -parallel workers
+// output of every host is buffered and printed as a whole when the host is completed
+func batch(c config, hosts []string) error {
+	hosts = uniqueTargets(c, hosts)
+	hosts = selectTargets(c, hosts)
+	if err := confirmBatch(c, hosts); err != nil {
+		return err
+	}
+	if c.nodisc {
+		var err error
+		if c, err = assumeDiscovery(c, hosts); err != nil {
+			return err
+		}
+	}
+	// progress is shown only on terminal of command line printer
+	p := &progress{total: len(hosts), locks: &hostLocks{locks: map[string]*sync.Mutex{}}}
+	if sp, ok := c.out.(*streamPrinter); ok {
+		p.w = sp.stderr
+		p.enabled = isTerminal(sp.stderr) && !c.quiet && !c.quietok && !structured(c)
+	}
+	// report is rewritten after every host, so it contains completed hosts even if the run is interrupted
+	// with -webhook-batch it is collected as well and posted when all hosts are done
+	if c.report != "" || c.whbatch {
+		p.report = &batchReport{Started: time.Now(), Total: len(hosts), Hosts: []hostReport{}}
+	}
+	if c.report != "" {
+		if err := p.report.write(c.report); err != nil {
+			return fmt.Errorf("cannot write report: %s", err)
+		}
+	}
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < c.parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range jobs {
+				batchHost(c, host, p)
+			}
+		}()
+	}
+	p.mu.Lock()
+	if c.output == "csv" {
+		if err := writeCSV(c, true, nil); err != nil {
+			p.mu.Unlock()
+			return err
+		}
+	}
+	p.show()
+	p.mu.Unlock()
+	for _, host := range hosts {
+		jobs <- host
+	}
+	close(jobs)
+	wg.Wait()
+
+	p.clear()
+	if c.output == "prometheus" {
+		sort.SliceStable(p.metrics, func(i, j int) bool { return p.metrics[i].host < p.metrics[j].host })
+		c.out.Result("%s", metrics(p.metrics))
+	}
+	if p.report != nil {
+		finished := time.Now()
+		p.report.Finished = &finished
+		p.report.Complete = true
+	}
+	if c.report != "" {
+		if err := p.report.write(c.report); err != nil {
+			c.out.Error("warning: cannot write report: %s\n", err)
+		}
+	}
+	if c.whbatch {
+		sendWebhook(c, p.report)
+	}
+	switch {
+	case c.quiet || c.quietok:
+	case c.vercreds:
+		c.out.Error("%d hosts: credentials accepted by %d, rejected by %d, %d failed otherwise\n", p.total, p.total-p.failed, p.denied, p.failed-p.denied)
+	default:
+		c.out.Error("%d hosts: %d succeeded, %d failed\n", p.total, p.total-p.failed, p.failed)
+	}
+	if p.denied > 1 {
+		c.out.Error("warning: %d hosts rejected credentials - repeated failed logins can lock out BMC accounts, verify credentials before running again\n", p.denied)
+	}
+	if p.failed > 0 {
+		return fmt.Errorf("%d of %d hosts failed", p.failed, p.total)
+	}
+	return nil
+}
+
+// assumeDiscovery resolves system URL and allowed actions on first -verify-first hosts and returns config assuming them for all hosts
+// hosts are expected to be identical, so any difference between verified hosts stops the batch before any host is processed
+func assumeDiscovery(c config, hosts []string) (config, error) {
+	n := c.verfirst
+	if n > len(hosts) {
+		n = len(hosts)
+	}
+	var sysurl string
+	var allowed []string
+	for i, host := range hosts[:n] {
+		hc := c
+		hc.host = host
+		hc.out = &recorder{}
+		if hc.user == "" || hc.pass == "" {
+			hc.user, hc.pass = netrcCredentials(host, hc.user, hc.pass)
+		}
+		sys, err := getSystem(hc)
+		if err != nil {
+			return c, fmt.Errorf("cannot resolve system of host %s to be assumed for all hosts - no host was processed: %w", host, err)
+		}
+		vals, err := allowedActions(hc, sys)
+		if err != nil {
+			return c, fmt.Errorf("cannot read allowed actions of host %s to be assumed for all hosts - no host was processed: %w", host, err)
+		}
+		if sys.OdataID == "" {
+			return c, fmt.Errorf("host %s returned system without @odata.id - cannot assume its URL for all hosts", host)
+		}
+		if i == 0 {
+			sysurl, allowed = sys.OdataID, vals
+			continue
+		}
+		if sys.OdataID != sysurl || strings.Join(vals, ",") != strings.Join(allowed, ",") {
+			return c, fmt.Errorf("hosts are not identical - %s has system %s allowing %s, %s has system %s allowing %s - no host was processed, run without -no-discovery",
+				hosts[0], sysurl, strings.Join(allowed, ", "), host, sys.OdataID, strings.Join(vals, ", "))
+		}
+	}
+	if !c.quiet {
+		c.out.Error("WARNING: assuming system %s allowing %s for all %d hosts (verified on %d) - discovery is skipped\n", sysurl, strings.Join(allowed, ", "), len(hosts), n)
+	}
+	c.sysurl = sysurl
+	// empty list still means the values were read, as opposed to nil
+	c.assumed = append([]string{}, allowed...)
+	return c, nil
+}
+
+// uniqueTargets returns targets without duplicates, host names are compared case-insensitively
+// warning is printed for every duplicate, so mistakes in hosts files and groups are noticed
+func uniqueTargets(c config, targets []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, t := range targets {
+		host, path := t, ""
+		if i := strings.IndexByte(t, '\t'); i >= 0 {
+			host, path = t[:i], t[i:]
+		}
+		key := strings.ToLower(host) + path
+		if seen[key] {
+			c.out.Error("warning: %s listed more than once - processed only once\n", strings.Replace(t, "\t", " ", 1))
+			continue
+		}
+		seen[key] = true
+		result = append(result, t)
+	}
+	return result
+}
+
+// selectTargets returns first -limit targets or random sample of -sample targets in original order
+// seed of random sample is printed, so the same sample can be selected again with -seed
+func selectTargets(c config, targets []string) []string {
+	switch {
+	case c.limit > 0 && c.limit < len(targets):
+		return targets[:c.limit]
+	case c.sample > 0 && c.sample < len(targets):
+		seed := c.seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		picked := rand.New(rand.NewSource(seed)).Perm(len(targets))[:c.sample]
+		sort.Ints(picked)
+		sample := make([]string, len(picked))
+		for i, p := range picked {
+			sample[i] = targets[p]
+		}
+		if !c.quiet {
+			c.out.Error("sampled %d of %d hosts (repeat with -seed %d)\n", len(sample), len(targets), seed)
+		}
+		return sample
+	}
+	return targets
+}
+
+// confirmBatch lists targets of destructive batch action and asks once for confirmation before any host is processed
+// confirmation is asked only if stdin is terminal and -yes is not set
+func confirmBatch(c config, targets []string) error {
+	if c.yes || !isTerminal(c.stdin) {
+		return nil
+	}
+	what, destructive := destructiveBatch(c)
+	if !destructive {
+		return nil
+	}
+	c.out.Error("%s will be performed on:\n", what)
+	for _, t := range targets {
+		c.out.Error("  %s\n", strings.Replace(t, "\t", " ", 1))
+	}
+	c.out.Error("Proceed with %s on %d hosts? [y/N] ", what, len(targets))
+	answer, _ := bufio.NewReader(c.stdin).ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+		return fmt.Errorf("not confirmed - no host was processed")
+	}
+	return nil
+}
+
+// destructiveBatch returns description of requested actions and true if any of them is destructive
+// actions are matched as typed on command line, so they are canonicalized like before they are performed
+func destructiveBatch(c config) (string, bool) {
+	var destructive bool
+	actions := make([]string, len(c.actions))
+	for i, a := range c.actions {
+		actions[i] = canonicalAction(a, nil)
+		destructive = destructive || describeAction(actions[i]).Destructive
+	}
+	if c.ensure == "Off" {
+		return "ensure Off", true
+	}
+	return strings.Join(actions, ","), destructive
+}
+
+// readTargets returns targets read from host<TAB>system path lines, skipping empty lines and comments starting with #
+// targets are returned as read, host and path are split for every target by batchHost
+func readTargets(r io.Reader) ([]string, error) {
+	var targets []string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 2 || strings.TrimSpace(fields[0]) == "" || !strings.HasPrefix(strings.TrimSpace(fields[1]), "/") {
+			return nil, fmt.Errorf("invalid target on line %d of standard input - expected host<TAB>system path: %q", n, line)
+		}
+		targets = append(targets, strings.TrimSpace(fields[0])+"\t"+strings.TrimSpace(fields[1]))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read targets from standard input: %s", err)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets read from standard input")
+	}
+	return targets, nil
+}
+
+// batchHost runs requested function for single host and prints its buffered output
+// hosts not supporting requested action are not counted as failed if -skip-unsupported is set
+// target read with -targets-stdin is host and system path separated by tab
+func batchHost(c config, target string, p *progress) {
+	rec := &recorder{}
+	hc := c
+	host, path, name := target, "", target
+	if i := strings.IndexByte(target, '\t'); i >= 0 {
+		host, path = target[:i], target[i+1:]
+		hc.sysurl = path
+		name = host + " " + path
+	}
+	hc.host = host
+	hc.out = rec
+	if c.whbatch {
+		hc.webhook = ""
+	}
+	var err error
+	if prof, ok := c.members[host]; ok {
+		hc, err = memberConfig(hc, prof)
+	}
+	if err == nil && hc.invurl != "" {
+		hc, err = resolveInventory(hc)
+	}
+	if hc.user == "" || hc.pass == "" {
+		hc.user, hc.pass = netrcCredentials(host, hc.user, hc.pass)
+	}
+	// the same BMC can be listed with different systems or reached using different names,
+	// it is never used by two workers at once, as concurrent actions could conflict
+	defer p.locks.lock(hc.host)()
+	if hc.trace {
+		hc.hook = traceRequest(hc)
+	}
+	// prometheus metrics of all hosts are printed together, as every metric family can be written only once
+	var samples []hostMetrics
+	if c.output == "prometheus" {
+		hc.samples = &samples
+	}
+	// power state read before actions is reused by discovery of dispatched function
+	hc.memo = newResourceMemo()
+	// power state before and after actions is recorded in the report
+	hr := hostReport{Host: host, SystemURL: path, Action: strings.Join(c.actions, ","), Status: "ok"}
+	if c.ensure != "" {
+		hr.Action = "ensure " + c.ensure
+	}
+	reportStates := (p.report != nil || c.output == "csv") && (len(c.actions) > 0 || c.ensure != "") && hc.user != "" && hc.pass != ""
+	// credentials are checked with single read of systems collection before dispatching, host rejecting them
+	// or locked out is not tried again, as repeated failed logins can lock the account; other errors are left
+	// to the dispatched function, as not all functions select single system, and the read is reused by its discovery
+	// -verify-creds performs exactly this check itself
+	if err == nil && hc.user != "" && hc.pass != "" && !c.vercreds {
+		if _, serr := redfishGet(hc, hostURL(hc, "/redfish/v1/Systems")); isAuthFailure(serr) || isLockout(serr) {
+			err = lockoutHint(serr)
+		}
+	}
+	if reportStates && err == nil {
+		hr.InitialState, _ = getPowerState(hc)
+	}
+	start := time.Now()
+	switch {
+	case err != nil:
+	case hc.user == "":
+		err = fmt.Errorf("missing -user name (no .netrc entry for the host)")
+	case hc.pass == "":
+		err = fmt.Errorf("missing -pass or -pass-file argument (no .netrc entry for the host)")
+	default:
+		err = dispatch(hc)
+	}
+	hr.Duration = time.Since(start).Seconds()
+	// host failed before its power state was read is still reported as failed scrape
+	if c.output == "prometheus" && len(samples) == 0 && err != nil {
+		samples = append(samples, hostMetrics{host: host, err: err, duration: time.Since(start)})
+	}
+	if reportStates && !isAuthFailure(err) {
+		hr.FinalState, _ = getPowerState(hc)
+	}
+	// csv row of host without actions reports its current power state
+	if c.output == "csv" && !reportStates && err == nil {
+		hr.FinalState, _ = getPowerState(hc)
+	}
+	var eerr *exitError
+	failed := err != nil && !(errors.As(err, &eerr) && eerr.code == exitUnsupported)
+	switch {
+	case failed:
+		hr.Status, hr.Error = "failed", err.Error()
+	case err != nil:
+		hr.Status, hr.Error = "skipped", err.Error()
+	}
+	switch {
+	case err != nil && c.jsonerr:
+		printJSONError(hc, err)
+	case failed:
+		hc.out.Error("error: host %s: %s\n", name, err)
+	case err != nil:
+		hc.out.Error("warning: host %s: %s\n", name, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	// with csv output only single row is written for every host, errors and warnings are printed as well
+	if c.output == "csv" {
+		rec.drop("result")
+	}
+	if failed || !c.quietok {
+		rec.replay(c.out)
+	}
+	if c.output == "csv" {
+		if err := writeCSV(c, false, [][]string{{name, hr.FinalState, hr.Action, hr.Status, hr.Error}}); err != nil {
+			c.out.Error("warning: cannot write csv row: %s\n", err)
+		}
+	}
+	p.metrics = append(p.metrics, samples...)
+	p.done++
+	if failed {
+		p.failed++
+	}
+	if isAuthFailure(err) {
+		p.denied++
+	}
+	if p.report != nil {
+		p.report.add(hr)
+	}
+	if c.report != "" {
+		if err := p.report.write(c.report); err != nil {
+			c.out.Error("warning: cannot write report: %s\n", err)
+		}
+	}
+	p.show()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// writeHosts writes hosts file listing test servers and returns its path
+func writeHosts(t *testing.T, srvs ...*httptest.Server) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "redpower")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	var b strings.Builder
+	for _, srv := range srvs {
+		b.WriteString(strings.TrimPrefix(srv.URL, "http://") + "\n")
+	}
+	path := filepath.Join(dir, "hosts")
+	if err := ioutil.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDestructiveBatch(t *testing.T) {
+	tests := []struct {
+		actions     []string
+		ensure      string
+		what        string
+		destructive bool
+	}{
+		{[]string{"On"}, "", "On", false},
+		{[]string{"on"}, "", "On", false},
+		{[]string{"forceoff"}, "", "ForceOff", true},
+		{[]string{"FORCERESTART"}, "", "ForceRestart", true},
+		{[]string{"on", "gracefulshutdown"}, "", "On,GracefulShutdown", true},
+		{nil, "Off", "ensure Off", true},
+		{nil, "On", "", false},
+	}
+	for _, tt := range tests {
+		what, destructive := destructiveBatch(config{actions: tt.actions, ensure: tt.ensure})
+		if what != tt.what || destructive != tt.destructive {
+			t.Errorf("%v ensure %q: %q, %t, want %q, %t", tt.actions, tt.ensure, what, destructive, tt.what, tt.destructive)
+		}
+	}
+}
+
+func TestBatchSkipsLockedOutHost(t *testing.T) {
+	var requests int32
+	_, denied := newTestServer(t, "u", "p", func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			mockError(w, http.StatusUnauthorized, "Base.1.8.AccountLocked", "account is locked")
+		})
+	})
+	_, accepted := newTestServer(t, "u", "p", nil)
+	hosts := writeHosts(t, denied, accepted)
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"redpower", "-scheme", "http", "-hosts", hosts, "-user", "u", "-pass", "p", "-quiet", "-action", "On"}, strings.NewReader(""), &stdout, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 hosts failed") {
+		t.Errorf("batch returned %v, want 1 of 2 hosts failed", err)
+	}
+	if !strings.Contains(stderr.String(), "appears to be locked out") {
+		t.Errorf("locked out host reported without lockout advice:\n%s", stderr.String())
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("host rejecting credentials received %d requests, want 1", n)
+	}
+}
+
+func TestBatchHostWithMultipleSystems(t *testing.T) {
+	// functions not selecting single system work on hosts with several systems like on single host
+	_, srv := newTestServer(t, "u", "p", func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/redfish/v1/Systems" {
+				mockJSON(w, http.StatusOK, map[string]interface{}{
+					"Members": []odataLink{{mockSystemURL}, {"/redfish/v1/Systems/2"}},
+				})
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"redpower", "-scheme", "http", "-hosts", writeHosts(t, srv), "-user", "u", "-pass", "p", "-quiet", "-list-systems"}, strings.NewReader(""), &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("-list-systems on host with multiple systems returned error: %s\n%s", err, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "/redfish/v1/Systems/2") {
+		t.Errorf("-list-systems output does not contain second system:\n%s", stdout.String())
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// type bootOption describes (partial) redfish boot option
+type bootOption struct {
+	BootOptionReference string `json:"BootOptionReference"`
+	DisplayName         string `json:"DisplayName"`
+	UefiDevicePath      string `json:"UefiDevicePath"`
+}
+
+// getBootOrder prints persistent boot order of the system with display names of boot options
+// currently only hosts with single computer system in redfish systems collection are supported
+func getBootOrder(c config) error {
+	sys, err := getSystem(c)
+	if err != nil {
+		return err
+	}
+	options, err := getBootOptions(c, sys)
+	if err != nil {
+		return err
+	}
+	if !c.quiet {
+		c.out.Info("host: %s boot order:\n", c.host)
+	}
+	w := tabwriter.NewWriter(resultWriter{c.out}, 0, 8, 2, ' ', 0)
+	if !c.quiet {
+		fmt.Fprintln(w, "REFERENCE\tNAME")
+	}
+	for _, ref := range sys.Boot.BootOrder {
+		fmt.Fprintf(w, "%s\t%s\n", ref, options[ref].DisplayName)
+	}
+	return w.Flush()
+}
+
+// setBootOrder sets persistent boot order of the system to boot option references specified with -set-bootorder
+// every reference must exist in boot options collection of the system
+func setBootOrder(c config) error {
+	var order []string
+	for _, ref := range strings.Split(c.setboot, ",") {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			return fmt.Errorf("empty boot option reference")
+		}
+		order = append(order, ref)
+	}
+	sys, err := getSystem(c)
+	if err != nil {
+		return err
+	}
+	options, err := getBootOptions(c, sys)
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, ref := range order {
+		if _, ok := options[ref]; !ok {
+			return fmt.Errorf("boot option %s not found on host %s", ref, c.host)
+		}
+		if seen[ref] {
+			return fmt.Errorf("boot option %s specified more than once", ref)
+		}
+		seen[ref] = true
+	}
+	payload := map[string]interface{}{"Boot": map[string]interface{}{"BootOrder": order}}
+	url := hostURL(c, sys.OdataID)
+	tracef(c, "boot order target: %s order: %s", url, strings.Join(order, ","))
+	if _, _, err := redfishPatch(c, url, payload, sys.Etag); err != nil {
+		return err
+	}
+	if !c.quiet {
+		c.out.Info("OK\n")
+	}
+	return nil
+}
+
+// listBootOptions prints boot options of the system in collection order with display names and UEFI device paths
+func listBootOptions(c config) error {
+	sys, err := getSystem(c)
+	if err != nil {
+		return err
+	}
+	options, err := fetchBootOptions(c, sys)
+	if err != nil {
+		return err
+	}
+	if !c.quiet {
+		c.out.Info("host: %s boot options:\n", c.host)
+	}
+	w := tabwriter.NewWriter(resultWriter{c.out}, 0, 8, 2, ' ', 0)
+	if !c.quiet {
+		fmt.Fprintln(w, "REFERENCE\tNAME\tUEFI DEVICE PATH")
+	}
+	for _, opt := range options {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", opt.BootOptionReference, opt.DisplayName, opt.UefiDevicePath)
+	}
+	return w.Flush()
+}
+
+// getBootOptions returns boot options of the system indexed by boot option reference
+func getBootOptions(c config, sys system) (map[string]bootOption, error) {
+	list, err := fetchBootOptions(c, sys)
+	if err != nil {
+		return nil, err
+	}
+	options := map[string]bootOption{}
+	for _, opt := range list {
+		options[opt.BootOptionReference] = opt
+	}
+	return options, nil
+}
+
+// fetchBootOptions returns boot options of the system in collection order
+func fetchBootOptions(c config, sys system) ([]bootOption, error) {
+	if sys.Boot.BootOptions.OdataID == "" {
+		return nil, fmt.Errorf("system does not provide boot options")
+	}
+	b, err := redfishGet(c, hostURL(c, sys.Boot.BootOptions.OdataID))
+	if err != nil {
+		return nil, err
+	}
+	members, err := parseRedfishCollection(b)
+	if err != nil {
+		return nil, err
+	}
+	var options []bootOption
+	for _, member := range members {
+		b, err := redfishGet(c, hostURL(c, member))
+		if err != nil {
+			return nil, err
+		}
+		var opt bootOption
+		if err := json.Unmarshal(b, &opt); err != nil {
+			return nil, fmt.Errorf("cannot parse boot option %s: %s", member, err)
+		}
+		options = append(options, opt)
+	}
+	return options, nil
+}
+
+// type bootProgress describes last boot progress state of the system printed with -boot-progress
+type bootProgress struct {
+	LastState     string `json:"lastState"`
+	LastStateTime string `json:"lastStateTime,omitempty"`
+}
+
+// printBootProgress prints last boot progress state of the system with time it was reached
+// OEM state is printed with its OEM name, state is unknown if the system does not report boot progress
+func printBootProgress(c config) error {
+	sys, err := getSystem(c)
+	if err != nil {
+		return err
+	}
+	bp := bootProgress{LastState: sys.BootProgress.LastState, LastStateTime: sys.BootProgress.LastStateTime}
+	if bp.LastState == "OEM" && sys.BootProgress.OemLastState != "" {
+		bp.LastState = "OEM " + sys.BootProgress.OemLastState
+	}
+	if bp.LastState == "" {
+		bp.LastState = "unknown"
+	}
+	if structured(c) {
+		return printResult(c, result{Host: c.host, PowerState: sys.PowerState, BootProgress: &bp})
+	}
+	if !c.quiet {
+		c.out.Info("host: %s boot progress: ", c.host)
+	}
+	if bp.LastStateTime != "" {
+		c.out.Result("%s (since %s)\n", bp.LastState, bp.LastStateTime)
+	} else {
+		c.out.Result("%s\n", bp.LastState)
+	}
+	return nil
+}
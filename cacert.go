@@ -0,0 +1,26 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// loadCACerts returns pool of certificates trusted for verification of host certificate
+// certificates from PEM file are added to copy of system trust store, or trusted exclusively if only is set
+func loadCACerts(path string, only bool) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read -cacert file: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !only {
+		if pool, err = x509.SystemCertPool(); err != nil {
+			return nil, fmt.Errorf("cannot load system trust store: %s - use -cacert-only to trust only -cacert certificates", err)
+		}
+	}
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no PEM encoded certificates found in -cacert file %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeCACert writes PEM file with certificate of the mock server and returns its path and the certificate
+func writeCACert(t *testing.T) (string, *x509.Certificate) {
+	t.Helper()
+	cert, err := mockCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "redpower")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, parsed
+}
+
+func TestLoadCACertsAppendsOrReplaces(t *testing.T) {
+	path, cert := writeCACert(t)
+	system, err := x509.SystemCertPool()
+	if err != nil || len(system.Subjects()) == 0 {
+		t.Skip("system trust store not available")
+	}
+	tests := []struct {
+		only bool
+		want int
+	}{
+		{false, len(system.Subjects()) + 1},
+		{true, 1},
+	}
+	for _, tt := range tests {
+		pool, err := loadCACerts(path, tt.only)
+		if err != nil {
+			t.Fatalf("only %t: unexpected error: %s", tt.only, err)
+		}
+		if got := len(pool.Subjects()); got != tt.want {
+			t.Errorf("only %t: pool contains %d certificates, want %d", tt.only, got, tt.want)
+		}
+		if _, err := cert.Verify(x509.VerifyOptions{Roots: pool, DNSName: "localhost"}); err != nil {
+			t.Errorf("only %t: certificate from -cacert not trusted: %s", tt.only, err)
+		}
+	}
+	// certificates are appended to a copy, system trust store itself is not modified
+	if after, _ := x509.SystemCertPool(); len(after.Subjects()) != len(system.Subjects()) {
+		t.Errorf("system trust store changed from %d to %d certificates", len(system.Subjects()), len(after.Subjects()))
+	}
+}
+
+func TestLoadCACertsRejectsFileWithoutCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redpower")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "empty.pem")
+	ioutil.WriteFile(path, []byte("not a certificate\n"), 0600)
+	if _, err := loadCACerts(path, false); err == nil {
+		t.Errorf("file without certificates accepted")
+	}
+}
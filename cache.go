@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheMu serializes cache file updates of hosts processed in parallel with -hosts
+var cacheMu sync.Mutex
+
+// type cacheEntry describes system URL resolved for the host
+type cacheEntry struct {
+	URL      string    `json:"url"`
+	Resolved time.Time `json:"resolved"`
+}
+
+// resolveSystemURL returns system URL specified with -system-url, from the cache if enabled and valid or resolves it using getSystemURL
+// returned bool is true when URL was taken from the cache
+func resolveSystemURL(c config) (string, bool, error) {
+	if c.sysurl != "" {
+		return hostURL(c, c.sysurl), false, nil
+	}
+	if !c.cache {
+		url, err := getSystemURL(c)
+		return url, false, err
+	}
+	// cached URL is absolute, so the scheme is part of the key
+	key := strings.Join([]string{c.scheme, c.host, c.muuid, c.mserial, c.filter}, "|")
+	cacheMu.Lock()
+	e, ok := loadCache()[key]
+	cacheMu.Unlock()
+	if ok && !c.refresh && time.Since(e.Resolved) < c.cachettl {
+		return e.URL, true, nil
+	}
+	url, err := getSystemURL(c)
+	if err != nil {
+		return "", false, err
+	}
+	// cache is loaded again, so entries saved by other hosts in the meantime are kept
+	cacheMu.Lock()
+	entries := loadCache()
+	entries[key] = cacheEntry{URL: url, Resolved: time.Now()}
+	err = saveCache(entries)
+	cacheMu.Unlock()
+	if err != nil && !c.quiet {
+		c.out.Error("warning: cannot update cache: %s\n", err)
+	}
+	return url, false, nil
+}
+
+// cachePath returns path of the cache file in user cache directory
+func cachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "redpower", "targets.json"), nil
+}
+
+// loadCache returns cache entries or empty map if cache file does not exist or cannot be read
+func loadCache() map[string]cacheEntry {
+	entries := make(map[string]cacheEntry)
+	path, err := cachePath()
+	if err != nil {
+		return entries
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return entries
+	}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return make(map[string]cacheEntry)
+	}
+	return entries
+}
+
+// saveCache writes cache entries to the cache file
+func saveCache(entries map[string]cacheEntry) error {
+	path, err := cachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
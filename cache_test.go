@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// cacheDir points user cache directory to temporary directory for the duration of the test
+func cacheDir(t *testing.T) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "redpower")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	setenv(t, "XDG_CACHE_HOME", dir)
+}
+
+func TestCacheKeyIncludesScheme(t *testing.T) {
+	cacheDir(t)
+	_, srv := newTestServer(t, "u", "p", nil)
+	if _, stderr, err := runTest(t, srv, "-user", "u", "-pass", "p", "-cache", "-get"); err != nil {
+		t.Fatalf("-cache returned error: %s\n%s", err, stderr)
+	}
+	entries := loadCache()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	e, ok := entries[strings.Join([]string{"http", host, "", "", ""}, "|")]
+	if !ok {
+		t.Fatalf("no cache entry for http://%s: %v", host, entries)
+	}
+	if e.URL != srv.URL+mockSystemURL {
+		t.Errorf("cached URL %s, want %s", e.URL, srv.URL+mockSystemURL)
+	}
+	// entry resolved with http must not be used for https, which the test server does not serve
+	if _, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-cache", "-get", "-scheme", "https", "-insecure", "-quiet"); err == nil {
+		t.Errorf("-scheme https used system URL cached for http")
+	}
+}
+
+func TestCacheKeepsEntriesOfParallelHosts(t *testing.T) {
+	cacheDir(t)
+	var srvs []*httptest.Server
+	for i := 0; i < 8; i++ {
+		_, srv := newTestServer(t, "u", "p", nil)
+		srvs = append(srvs, srv)
+	}
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"redpower", "-scheme", "http", "-hosts", writeHosts(t, srvs...), "-user", "u", "-pass", "p", "-quiet", "-cache", "-get"}, strings.NewReader(""), &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("batch with -cache returned error: %s\n%s", err, stderr.String())
+	}
+	entries := loadCache()
+	for _, srv := range srvs {
+		if _, ok := entries[strings.Join([]string{"http", strings.TrimPrefix(srv.URL, "http://"), "", "", ""}, "|")]; !ok {
+			t.Errorf("no cache entry for %s", srv.URL)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+)
+
+// type chassis describes (partial) redfish chassis
+type chassis struct {
+	PowerState string `json:"PowerState"`
+	Links      struct {
+		ComputerSystems []struct {
+			OdataID string `json:"@odata.id"`
+		} `json:"ComputerSystems"`
+	} `json:"Links"`
+	Actions struct {
+		ChassisReset resetAction `json:"#Chassis.Reset"`
+	} `json:"Actions"`
+}
+
+// getChassis prints power state of the chassis or, with -all-systems, power state of every system contained in the chassis
+// currently only hosts with single chassis in redfish chassis collection are supported
+func getChassis(c config) error {
+	url, err := getChassisURL(c)
+	if err != nil {
+		return err
+	}
+	b, err := redfishGet(c, url)
+	if err != nil {
+		return err
+	}
+	var ch chassis
+	if err := json.Unmarshal(b, &ch); err != nil {
+		return err
+	}
+	if !c.allsys {
+		if !c.quiet {
+			c.out.Info("host: %s chassis power state: ", c.host)
+		}
+		c.out.Result("%s\n", ch.PowerState)
+		return nil
+	}
+
+	if !c.quiet {
+		c.out.Info("host: %s chassis systems power state:\n", c.host)
+	}
+	w := tabwriter.NewWriter(resultWriter{c.out}, 0, 8, 2, ' ', 0)
+	if !c.quiet {
+		fmt.Fprintln(w, "SYSTEM\tPOWER STATE")
+	}
+	for _, link := range ch.Links.ComputerSystems {
+		b, err := redfishGet(c, hostURL(c, link.OdataID))
+		if err != nil {
+			return err
+		}
+		var sys system
+		if err := json.Unmarshal(b, &sys); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\n", link.OdataID, sys.PowerState)
+	}
+	return w.Flush()
+}
+
+// getChassisURL returns URL for redfish chassis or error if 0 or more than 1 chassis is found in the chassis collection
+func getChassisURL(c config) (string, error) {
+	url := hostURL(c, "/redfish/v1/Chassis")
+	b, err := redfishGet(c, url)
+	if err != nil {
+		return "", err
+	}
+	members, err := parseRedfishCollection(b)
+	if err != nil {
+		return "", err
+	}
+	switch l := len(members); {
+	case l == 0:
+		return "", fmt.Errorf("no chassis found in the redfish chassis collection")
+	case l > 1:
+		return "", fmt.Errorf("multiple chassis found in the redfish chassis collection - not supported")
+	}
+	return hostURL(c, members[0]), nil
+}
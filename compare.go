@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// type comparedField describes value of single field of both compared results
+type comparedField struct {
+	name string
+	a    string
+	b    string
+}
+
+// compare prints power state of the host next to other host or snapshot file specified with -compare
+// and fails if they differ, allowed actions are compared as well with -compare-actions
+// snapshot is json result saved with -output json, fields missing in the snapshot are not compared
+func compare(c config) error {
+	a, err := snapshot(c, c.cmpacts)
+	if err != nil {
+		return err
+	}
+	var b result
+	if fi, serr := os.Stat(c.compare); serr == nil && fi.Mode().IsRegular() {
+		if b, err = loadSnapshot(c.compare); err != nil {
+			return err
+		}
+		// snapshot name is more useful than host it was taken from
+		b.Host = c.compare
+	} else {
+		oc := c
+		oc.host = c.compare
+		if b, err = snapshot(oc, c.cmpacts); err != nil {
+			return err
+		}
+	}
+
+	fields := []comparedField{{"powerState", a.PowerState, b.PowerState}}
+	if c.cmpacts {
+		fields = append(fields, comparedField{"allowedActions", actionNames(a.AllowedActions), actionNames(b.AllowedActions)})
+	}
+	w := tabwriter.NewWriter(resultWriter{c.out}, 0, 8, 2, ' ', 0)
+	if !c.quiet {
+		fmt.Fprintf(w, "\t%s\t%s\t\n", a.Host, b.Host)
+	}
+	differ := 0
+	for _, f := range fields {
+		mark := ""
+		switch {
+		case f.b == "":
+			mark = "(not in snapshot)"
+		case f.a != f.b:
+			mark = "DIFFERS"
+			differ++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.name, f.a, f.b, mark)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if differ > 0 {
+		return fmt.Errorf("%s and %s differ", a.Host, b.Host)
+	}
+	return nil
+}
+
+// snapshot returns power state and optionally allowed actions of the host
+func snapshot(c config, actions bool) (result, error) {
+	sys, err := getSystem(c)
+	if err != nil {
+		return result{}, err
+	}
+	res := result{Host: c.host, PowerState: sys.PowerState}
+	if !actions {
+		return res, nil
+	}
+	vals, err := allowedActions(c, sys)
+	if err != nil {
+		return result{}, err
+	}
+	for _, val := range vals {
+		res.AllowedActions = append(res.AllowedActions, describeAction(val))
+	}
+	return res, nil
+}
+
+// loadSnapshot reads json result saved with -output json
+func loadSnapshot(path string) (result, error) {
+	var res result
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return res, fmt.Errorf("cannot read snapshot: %s", err)
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return res, fmt.Errorf("cannot parse snapshot %s: %s", path, err)
+	}
+	return res, nil
+}
+
+// actionNames returns comma separated names of actions
+func actionNames(actions []actionInfo) string {
+	names := make([]string, len(actions))
+	for i, a := range actions {
+		names[i] = a.Action
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// type conformanceCheck describes single step of the conformance check
+// check returns detail printed after passed check or error
+type conformanceCheck struct {
+	name  string
+	check func() (string, error)
+}
+
+// conformance verifies that the host provides everything needed for power control and prints pass/fail checklist
+// checks depending on a failed one are skipped
+func conformance(c config) error {
+	var sys system
+	var members []member
+	checks := []conformanceCheck{
+		{"service root reports RedfishVersion", func() (string, error) {
+			b, err := redfishGet(c, hostURL(c, "/redfish/v1"))
+			if err != nil {
+				return "", err
+			}
+			var root struct {
+				RedfishVersion string `json:"RedfishVersion"`
+			}
+			if err := json.Unmarshal(b, &root); err != nil {
+				return "", err
+			}
+			if root.RedfishVersion == "" {
+				return "", fmt.Errorf("RedfishVersion is missing")
+			}
+			return root.RedfishVersion, nil
+		}},
+		{"systems collection has at least one member", func() (string, error) {
+			b, err := redfishGet(c, hostURL(c, "/redfish/v1/Systems"))
+			if err != nil {
+				return "", err
+			}
+			if members, err = parseRedfishMembers(b); err != nil {
+				return "", err
+			}
+			if len(members) == 0 {
+				return "", fmt.Errorf("systems collection is empty")
+			}
+			return fmt.Sprintf("%d members", len(members)), nil
+		}},
+		{"system can be selected", func() (string, error) {
+			var err error
+			if sys, err = getSystem(c); err != nil {
+				return "", err
+			}
+			return sys.OdataID, nil
+		}},
+		{"system exposes #ComputerSystem.Reset with target", func() (string, error) {
+			if sys.Actions.ComputerSystemReset.Target == "" {
+				return "", fmt.Errorf("reset action target is missing")
+			}
+			return sys.Actions.ComputerSystemReset.Target, nil
+		}},
+		{"reset action lists allowable values", func() (string, error) {
+			vals, err := allowedActions(c, sys)
+			if err != nil {
+				return "", err
+			}
+			if len(vals) == 0 {
+				return "", fmt.Errorf("no allowable values")
+			}
+			return strings.Join(vals, ", "), nil
+		}},
+		{"system GET round-trips", func() (string, error) {
+			b, err := redfishGet(c, hostURL(c, sys.OdataID))
+			if err != nil {
+				return "", err
+			}
+			var again system
+			if err := json.Unmarshal(b, &again); err != nil {
+				return "", err
+			}
+			if again.OdataID != sys.OdataID {
+				return "", fmt.Errorf("got %s instead of %s", again.OdataID, sys.OdataID)
+			}
+			return again.PowerState, nil
+		}},
+	}
+
+	if !c.quiet {
+		c.out.Info("host: %s conformance:\n", c.host)
+	}
+	failed := 0
+	for _, cc := range checks {
+		if failed > 0 {
+			c.out.Result("SKIP  %s\n", cc.name)
+			continue
+		}
+		detail, err := cc.check()
+		if err != nil {
+			c.out.Result("FAIL  %s: %s\n", cc.name, err)
+			failed++
+			continue
+		}
+		c.out.Result("PASS  %s (%s)\n", cc.name, detail)
+	}
+	if failed > 0 {
+		return fmt.Errorf("conformance check failed on host %s", c.host)
+	}
+	return nil
+}
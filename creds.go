@@ -0,0 +1,20 @@
+package main
+
+import "fmt"
+
+// verifyCredentials checks that the host accepts credentials without performing any other operation
+// systems collection is read instead of service root, as redfish allows reading service root without authentication
+func verifyCredentials(c config) error {
+	_, err := redfishGet(c, hostURL(c, "/redfish/v1/Systems"))
+	if isAuthFailure(err) && !isLockout(err) {
+		return fmt.Errorf("credentials rejected: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+	if !c.quiet {
+		c.out.Info("host: %s credentials: ", c.host)
+	}
+	c.out.Result("accepted\n")
+	return nil
+}
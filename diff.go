@@ -0,0 +1,21 @@
+package main
+
+// type stateDiff describes power state of the system before and after actions
+type stateDiff struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// diffState reads power state after actions and reports it together with state read before them
+// with -wait the state is already settled, otherwise it is single read which may show transitional state
+func diffState(c config, sys system) (*stateDiff, error) {
+	after, err := pollPowerState(c, sys)
+	if err != nil {
+		return nil, err
+	}
+	d := &stateDiff{Before: sys.PowerState, After: after}
+	if !structured(c) {
+		c.out.Result("host: %s PowerState: %s -> %s\n", c.host, d.Before, d.After)
+	}
+	return d, nil
+}
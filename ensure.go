@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ensureActions lists actions tried in order to reach desired power state, first one allowed by the host is performed
+var ensureActions = map[string][]string{
+	"On":  {"On", "ForceOn", "PushPowerButton"},
+	"Off": {"GracefulShutdown", "ForceOff", "PushPowerButton"},
+}
+
+// ensure performs action resulting in power state requested with -ensure only if current power state differs
+// system which is already transitioning to requested state is not touched
+func ensure(c config) error {
+	sys, err := getSystem(c)
+	if err != nil {
+		return err
+	}
+	state := sys.PowerState
+	if state == c.ensure || state == "Powering"+c.ensure {
+		if structured(c) {
+			return printResult(c, result{Host: c.host, PowerState: state})
+		}
+		if !c.quiet {
+			c.out.Info("host: %s already %s (no action)\n", c.host, state)
+		}
+		return nil
+	}
+	allowed, err := allowedActions(c, sys)
+	if err != nil {
+		return err
+	}
+	candidates := ensureActions[c.ensure]
+	if c.ensure == "On" && state == "Paused" {
+		candidates = append([]string{"Resume"}, candidates...)
+	}
+	// without allowable values the first action is tried
+	act := candidates[0]
+	if len(allowed) > 0 {
+		act = ""
+		for _, a := range candidates {
+			if containsFold(allowed, a) {
+				act = a
+				break
+			}
+		}
+		if act == "" {
+			return fmt.Errorf("none of %s actions is supported by host %s", strings.Join(candidates, ", "), c.host)
+		}
+	}
+	tracef(c, "power state: %s desired: %s action: %s", state, c.ensure, act)
+	c.actions = actionList{act}
+	return action(c)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// type event describes (partial) redfish event record
+type event struct {
+	EventType         string    `json:"EventType"`
+	EventTimestamp    string    `json:"EventTimestamp"`
+	MessageID         string    `json:"MessageId"`
+	Message           string    `json:"Message"`
+	OriginOfCondition odataLink `json:"OriginOfCondition"`
+}
+
+// subscribe streams power related events using server-sent events of redfish event service until interrupted
+// subscription created by the BMC for the stream is removed when the connection is closed
+func subscribe(c config) error {
+	b, err := redfishGet(c, hostURL(c, "/redfish/v1/EventService"))
+	var rerr *redfishError
+	if errors.As(err, &rerr) && rerr.statusCode == http.StatusNotFound {
+		return fmt.Errorf("BMC does not provide redfish event service")
+	}
+	if err != nil {
+		return err
+	}
+	var es struct {
+		ServiceEnabled     *bool  `json:"ServiceEnabled"`
+		ServerSentEventURI string `json:"ServerSentEventUri"`
+	}
+	if err := json.Unmarshal(b, &es); err != nil {
+		return err
+	}
+	switch {
+	case es.ServiceEnabled != nil && !*es.ServiceEnabled:
+		return fmt.Errorf("redfish event service is disabled on the BMC")
+	case es.ServerSentEventURI == "":
+		return fmt.Errorf("BMC event service does not support server-sent events (push subscriptions are not supported)")
+	}
+
+	// stream stays open until interrupted, so it cannot be limited by request timeout
+	c, interrupted, stop := interruptible(c)
+	defer stop()
+	client := *c.client
+	client.Timeout = 0
+	c.client = &client
+
+	url := hostURL(c, es.ServerSentEventURI)
+	tracef(c, "event stream: %s", url)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	setHeaders(c, req)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := doRequest(c, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cannot open event stream - status code: %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	if !c.quiet {
+		c.out.Info("host: %s waiting for power events (interrupt to stop) ...\n", c.host)
+	}
+
+	// events are separated with empty line, data of single event can be split into multiple lines
+	var data []string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data:") {
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		}
+		if line != "" || len(data) == 0 {
+			continue
+		}
+		printEvents(c, strings.Join(data, "\n"))
+		data = nil
+	}
+	if interrupted() {
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("event stream failed: %s", err)
+	}
+	return fmt.Errorf("event stream closed by the BMC")
+}
+
+// printEvents prints power related event records from data of single server-sent event
+func printEvents(c config, data string) {
+	var payload struct {
+		Events []event `json:"Events"`
+	}
+	if err := json.Unmarshal([]byte(data), &payload); err != nil {
+		tracef(c, "cannot parse event: %s", err)
+		return
+	}
+	for _, e := range payload.Events {
+		if !powerEvent(e) {
+			continue
+		}
+		c.out.Result("%s %s %s %s\n", e.EventTimestamp, e.OriginOfCondition.OdataID, e.MessageID, e.Message)
+	}
+}
+
+// powerEvent returns true if event is related to power state
+func powerEvent(e event) bool {
+	return strings.Contains(strings.ToLower(e.MessageID+" "+e.Message), "power")
+}
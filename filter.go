@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// matchFilter returns true if json document has property selected by -filter path equal to -filter value
+// path uses dot notation, keys containing dots (like @odata.id) are matched as a whole,
+// arrays are indexed with numbers or match if any of their elements matches
+func matchFilter(b []byte, filter string) (bool, error) {
+	path, value := splitFilter(filter)
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return false, err
+	}
+	for _, v := range jsonPathValues(doc, strings.Split(path, ".")) {
+		if strings.EqualFold(jsonString(v), value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// splitFilter returns property path and expected value of the filter in property=value format
+func splitFilter(filter string) (string, string) {
+	i := strings.Index(filter, "=")
+	if i < 0 {
+		return filter, ""
+	}
+	return strings.TrimSpace(filter[:i]), strings.TrimSpace(filter[i+1:])
+}
+
+// jsonPathValues returns all values found at path in decoded json document
+func jsonPathValues(v interface{}, path []string) []interface{} {
+	if len(path) == 0 {
+		if arr, ok := v.([]interface{}); ok {
+			return arr
+		}
+		return []interface{}{v}
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		// longest key first, so keys containing dots take precedence
+		var values []interface{}
+		for i := len(path); i > 0; i-- {
+			if next, ok := v[strings.Join(path[:i], ".")]; ok {
+				values = append(values, jsonPathValues(next, path[i:])...)
+			}
+		}
+		return values
+	case []interface{}:
+		if i, err := strconv.Atoi(path[0]); err == nil {
+			if i < 0 || i >= len(v) {
+				return nil
+			}
+			return jsonPathValues(v[i], path[1:])
+		}
+		var values []interface{}
+		for _, e := range v {
+			values = append(values, jsonPathValues(e, path)...)
+		}
+		return values
+	}
+	return nil
+}
+
+// jsonString returns decoded json scalar as string, objects and arrays are returned as json
+func jsonString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case nil:
+		return "null"
+	case bool, float64:
+		return fmt.Sprint(v)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// extractField returns value of property selected with -raw-field path in json document or error if it does not exist
+// path uses dot notation, arrays are indexed with numbers in brackets (like Members[0]) and keys containing dots
+// can be quoted in brackets (like ["@odata.id"])
+func extractField(b []byte, field string) (interface{}, error) {
+	path, err := splitFieldPath(field)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("response is not valid json: %s", err)
+	}
+	v, ok := jsonPathValue(doc, path)
+	if !ok {
+		return nil, fmt.Errorf("field %s not found in response", field)
+	}
+	return v, nil
+}
+
+// splitFieldPath returns elements of property path in dot and bracket notation
+func splitFieldPath(field string) ([]string, error) {
+	var path []string
+	var elem strings.Builder
+	for i := 0; i < len(field); i++ {
+		switch field[i] {
+		case '.':
+			if elem.Len() > 0 {
+				path = append(path, elem.String())
+				elem.Reset()
+			}
+		case '[':
+			end := strings.IndexByte(field[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid field path %s - missing ]", field)
+			}
+			if elem.Len() > 0 {
+				path = append(path, elem.String())
+				elem.Reset()
+			}
+			key := strings.TrimSpace(field[i+1 : i+end])
+			if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') && key[len(key)-1] == key[0] {
+				key = key[1 : len(key)-1]
+			}
+			if key == "" {
+				return nil, fmt.Errorf("invalid field path %s - empty brackets", field)
+			}
+			path = append(path, key)
+			i += end
+		default:
+			elem.WriteByte(field[i])
+		}
+	}
+	if elem.Len() > 0 {
+		path = append(path, elem.String())
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("empty field path")
+	}
+	return path, nil
+}
+
+// jsonPathValue returns single value found at path in decoded json document, arrays are only indexed with numbers
+func jsonPathValue(v interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		// longest key first, so keys containing dots take precedence
+		for i := len(path); i > 0; i-- {
+			if next, ok := v[strings.Join(path[:i], ".")]; ok {
+				if value, ok := jsonPathValue(next, path[i:]); ok {
+					return value, true
+				}
+			}
+		}
+	case []interface{}:
+		if i, err := strconv.Atoi(path[0]); err == nil && i >= 0 && i < len(v) {
+			return jsonPathValue(v[i], path[1:])
+		}
+	}
+	return nil, false
+}
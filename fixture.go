@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// type fixture holds http exchanges recorded with -record and served with -replay
+// exchanges are keyed by method and path with query, host is not recorded
+type fixture struct {
+	mu        sync.Mutex
+	Exchanges []fixtureExchange `json:"exchanges"`
+	served    map[string]int
+}
+
+// type fixtureExchange describes single recorded request and its response
+type fixtureExchange struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+// type recordingTransport passes requests to the next transport and records their responses to the fixture
+type recordingTransport struct {
+	c    config
+	next http.RoundTripper
+	f    *fixture
+}
+
+// RoundTrip performs request and records its response, body of event stream is not recorded
+// sensitive headers are redacted, so the fixture can be shared
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	e := fixtureExchange{Method: req.Method, Path: req.URL.RequestURI(), Status: resp.StatusCode, Header: http.Header{}}
+	for name, values := range resp.Header {
+		if name == "Date" || name == "Content-Length" {
+			continue
+		}
+		for _, value := range values {
+			e.Header.Add(name, redactHeader(t.c, name, value))
+		}
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, t.c.maxbody))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b), resp.Body), resp.Body}
+		e.Body = redactBody(string(b))
+	}
+	t.f.mu.Lock()
+	t.f.Exchanges = append(t.f.Exchanges, e)
+	t.f.mu.Unlock()
+	return resp, nil
+}
+
+// type replayTransport serves responses from the fixture instead of the network
+type replayTransport struct {
+	f *fixture
+}
+
+// RoundTrip returns recorded response of the request
+// responses recorded for the same request are served in recorded order, the last one is repeated (like when polling)
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	key := req.Method + " " + req.URL.RequestURI()
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	var matching []fixtureExchange
+	for _, e := range t.f.Exchanges {
+		if e.Method+" "+e.Path == key {
+			matching = append(matching, e)
+		}
+	}
+	if len(matching) == 0 {
+		return nil, fmt.Errorf("no recorded response for %s in -replay fixture", key)
+	}
+	n := t.f.served[key]
+	if n >= len(matching) {
+		n = len(matching) - 1
+	}
+	t.f.served[key]++
+	e := matching[n]
+	header := http.Header{}
+	for name, values := range e.Header {
+		header[name] = append([]string(nil), values...)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}, nil
+}
+
+// loadFixture reads fixture recorded with -record
+func loadFixture(path string) (*fixture, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read replay fixture: %s", err)
+	}
+	f := &fixture{served: map[string]int{}}
+	if err := json.Unmarshal(b, f); err != nil {
+		return nil, fmt.Errorf("cannot parse replay fixture %s: %s", path, err)
+	}
+	return f, nil
+}
+
+// write saves recorded exchanges to the file readable only by the owner
+func (f *fixture) write(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Exchanges == nil {
+		f.Exchanges = []fixtureExchange{}
+	}
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0600)
+}
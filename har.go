@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// type harLog collects requests and responses written to -har file in HAR 1.2 format
+// it is shared by all hosts processed in batch mode
+type harLog struct {
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+// type harEntry describes single request with its response
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+// type harRequest describes recorded request
+type harRequest struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []struct{}  `json:"cookies"`
+	Headers     []harHeader `json:"headers"`
+	QueryString []harHeader `json:"queryString"`
+	PostData    *harContent `json:"postData,omitempty"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+// type harResponse describes recorded response, status is 0 if no response was received
+type harResponse struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []struct{}  `json:"cookies"`
+	Headers     []harHeader `json:"headers"`
+	Content     harContent  `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+// type harHeader describes header or query string parameter
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// type harContent describes request or response body
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// type harTimings describes request timing, only waiting for the response is measured
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// recordHAR adds request and response to -har log with credentials redacted
+// response body is read and replaced, so it can still be read by the caller, event streams are recorded without body
+func recordHAR(c config, req *http.Request, resp *http.Response, start time.Time, err error) {
+	e := harEntry{StartedDateTime: start, Time: msec(time.Since(start))}
+	e.Timings.Wait = e.Time
+	e.Request = harRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: req.Proto,
+		Cookies:     []struct{}{},
+		Headers:     harHeaders(c, req.Header),
+		QueryString: []harHeader{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			e.Request.QueryString = append(e.Request.QueryString, harHeader{name, v})
+		}
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			b, _ := ioutil.ReadAll(body)
+			body.Close()
+			e.Request.BodySize = len(b)
+			e.Request.PostData = &harContent{Size: len(b), MimeType: req.Header.Get("Content-Type"), Text: redactBody(string(b))}
+		}
+	}
+	e.Response = harResponse{Cookies: []struct{}{}, Headers: []harHeader{}, HeadersSize: -1, BodySize: -1}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if resp != nil {
+		e.Response.Status = resp.StatusCode
+		e.Response.StatusText = http.StatusText(resp.StatusCode)
+		e.Response.HTTPVersion = resp.Proto
+		e.Response.Headers = harHeaders(c, resp.Header)
+		e.Response.RedirectURL = resp.Header.Get("Location")
+		e.Response.Content.MimeType = resp.Header.Get("Content-Type")
+		if !strings.HasPrefix(e.Response.Content.MimeType, "text/event-stream") {
+			// only up to -max-response-size is recorded, the rest is left for the caller to reject
+			b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, c.maxbody))
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(b), resp.Body), resp.Body}
+			e.Response.BodySize = len(b)
+			e.Response.Content.Size = len(b)
+			e.Response.Content.Text = redactBody(string(b))
+		}
+	}
+	c.har.mu.Lock()
+	c.har.entries = append(c.har.entries, e)
+	c.har.mu.Unlock()
+}
+
+// harHeaders returns headers sorted by name with values of sensitive headers replaced with ****
+func harHeaders(c config, h http.Header) []harHeader {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := []harHeader{}
+	for _, name := range names {
+		for _, value := range h[name] {
+			result = append(result, harHeader{name, redactHeader(c, name, value)})
+		}
+	}
+	return result
+}
+
+// msec returns duration in milliseconds
+func msec(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// write saves recorded requests to the file readable only by the owner
+func (h *harLog) write(path string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var doc struct {
+		Log struct {
+			Version string `json:"version"`
+			Creator struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"creator"`
+			Entries []harEntry `json:"entries"`
+		} `json:"log"`
+	}
+	doc.Log.Version = "1.2"
+	doc.Log.Creator.Name = "redpower"
+	doc.Log.Creator.Version = version
+	doc.Log.Entries = h.entries
+	if doc.Log.Entries == nil {
+		doc.Log.Entries = []harEntry{}
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0600)
+}
@@ -0,0 +1,45 @@
+package main
+
+import "strconv"
+
+// type nodeInfo describes identity and size of the system printed with -info
+type nodeInfo struct {
+	Model        string  `json:"model,omitempty"`
+	SerialNumber string  `json:"serialNumber,omitempty"`
+	Processors   int     `json:"processorCount"`
+	MemoryGiB    float64 `json:"memoryGiB"`
+	BiosVersion  string  `json:"biosVersion,omitempty"`
+}
+
+// printInfo prints power state of the system with its model, serial number, processor count, memory size and BIOS version
+// values missing in the system resource are printed as unknown
+func printInfo(c config) error {
+	sys, err := getSystem(c)
+	if err != nil {
+		return err
+	}
+	info := nodeInfo{
+		Model:        sys.Model,
+		SerialNumber: sys.SerialNumber,
+		Processors:   sys.ProcessorSummary.Count,
+		MemoryGiB:    sys.MemorySummary.TotalSystemMemoryGiB,
+		BiosVersion:  sys.BiosVersion,
+	}
+	if structured(c) {
+		return printResult(c, result{Host: c.host, PowerState: sys.PowerState, Info: &info})
+	}
+	if !c.quiet {
+		c.out.Info("host: %s ", c.host)
+	}
+	c.out.Result("power state: %s model: %s serial: %s processors: %s memory: %s bios: %s\n", orUnknown(sys.PowerState), orUnknown(info.Model),
+		orUnknown(info.SerialNumber), orUnknown(strconv.Itoa(info.Processors)), orUnknown(strconv.FormatFloat(info.MemoryGiB, 'f', -1, 64)+" GiB"), orUnknown(info.BiosVersion))
+	return nil
+}
+
+// orUnknown returns s or unknown if s is empty or zero
+func orUnknown(s string) string {
+	if s == "" || s == "0" || s == "0 GiB" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// type inventoryEntry describes BMC address and optional credentials of logical host returned by inventory service
+type inventoryEntry struct {
+	BMC  string `json:"bmc"`
+	User string `json:"user"`
+	Pass string `json:"pass"`
+}
+
+// resolveInventory looks up logical host name at -inventory-url and returns config with its BMC address
+// credentials from the inventory are used only if they were not provided otherwise, password only with matching user
+func resolveInventory(c config) (config, error) {
+	u := strings.TrimRight(c.invurl, "/") + "/" + url.PathEscape(c.host)
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return c, fmt.Errorf("invalid -inventory-url: %s", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.agent)
+	if c.invauth != "" {
+		req.Header.Set("Authorization", c.invauth)
+	}
+	client := &http.Client{Timeout: time.Second * time.Duration(c.timeout)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return c, fmt.Errorf("inventory lookup of host %s failed: %s", c.host, err)
+	}
+	defer resp.Body.Close()
+	body, err := readBody(c, resp)
+	if err != nil {
+		return c, fmt.Errorf("inventory lookup of host %s failed: %s", c.host, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return c, fmt.Errorf("host %s not found in inventory", c.host)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return c, fmt.Errorf("inventory lookup of host %s not authorized - status code: %d (%s), check -inventory-auth", c.host, resp.StatusCode, http.StatusText(resp.StatusCode))
+	default:
+		return c, fmt.Errorf("inventory lookup of host %s failed - status code: %d (%s)", c.host, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	var e inventoryEntry
+	if err := json.Unmarshal(body, &e); err != nil {
+		return c, fmt.Errorf("cannot parse inventory entry of host %s: %s", c.host, err)
+	}
+	if e.BMC == "" {
+		return c, fmt.Errorf("inventory entry of host %s has no bmc address", c.host)
+	}
+	tracef(c, "inventory: host %s resolved to %s", c.host, e.BMC)
+	c.host = e.BMC
+	if c.user == "" && c.token == "" {
+		c.user = e.User
+	}
+	if c.pass == "" && c.token == "" && c.user == e.User {
+		c.pass = e.Pass
+	}
+	return c, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// listAll prints allowed reset types of every system, chassis and manager found on specified host, grouped by collection
+func listAll(c config) error {
+	b, err := redfishGet(c, hostURL(c, "/redfish/v1"))
+	if err != nil {
+		return err
+	}
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(b, &root); err != nil {
+		return err
+	}
+	if !c.quiet {
+		c.out.Info("host: %s reset actions:\n", c.host)
+	}
+	for _, name := range []string{"Systems", "Chassis", "Managers"} {
+		var link struct {
+			OdataID string `json:"@odata.id"`
+		}
+		if err := json.Unmarshal(root[name], &link); err != nil || link.OdataID == "" {
+			continue
+		}
+		b, err := redfishGet(c, hostURL(c, link.OdataID))
+		if err != nil {
+			return err
+		}
+		members, err := parseRedfishCollection(b)
+		if err != nil {
+			return err
+		}
+		c.out.Result("%s:\n", name)
+		for _, member := range members {
+			actions, err := resetActions(c, member)
+			if err != nil {
+				return err
+			}
+			for _, a := range actions {
+				c.out.Result("  %s %s: %s\n", member, a.name, strings.Join(a.values, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// type namedValues holds allowed reset types of the named reset action
+type namedValues struct {
+	name   string
+	values []string
+}
+
+// resetActions returns allowed reset types of all standard reset actions (like #Chassis.Reset) of the resource at specified path
+func resetActions(c config, path string) ([]namedValues, error) {
+	b, err := redfishGet(c, hostURL(c, path))
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Actions map[string]json.RawMessage `json:"Actions"`
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+	var result []namedValues
+	for name, raw := range res.Actions {
+		if !strings.HasPrefix(name, "#") || !strings.HasSuffix(name, ".Reset") {
+			continue
+		}
+		var ra resetAction
+		if err := json.Unmarshal(raw, &ra); err != nil {
+			return nil, err
+		}
+		vals, err := allowableValues(c, ra)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, namedValues{name: name, values: vals})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// lockoutKeywords lists parts of message ids and messages used by BMCs to report locked account or delayed login
+var lockoutKeywords = []string{"lockout", "locked", "loginattemptdelayed", "toomanyattempts", "too many"}
+
+// isAuthFailure returns true if err is response rejecting credentials, repeated failures can lock the account
+func isAuthFailure(err error) bool {
+	var rerr *redfishError
+	return errors.As(err, &rerr) && (rerr.statusCode == http.StatusUnauthorized || rerr.statusCode == http.StatusForbidden)
+}
+
+// isLockout returns true if err is response reporting that BMC account is locked out
+func isLockout(err error) bool {
+	var rerr *redfishError
+	if !errors.As(err, &rerr) {
+		return false
+	}
+	switch rerr.statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+	default:
+		return false
+	}
+	msg := strings.ToLower(rerr.messageID + " " + rerr.message)
+	for _, k := range lockoutKeywords {
+		if strings.Contains(msg, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// lockoutHint returns err with cooldown advice if it reports locked BMC account
+func lockoutHint(err error) error {
+	if !isLockout(err) {
+		return err
+	}
+	return fmt.Errorf("%w - BMC account appears to be locked out after failed logins, wait until the lockout expires (usually several minutes) before trying again", err)
+}
@@ -1,15 +1,26 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 )
 
@@ -22,66 +33,694 @@ var (
 
 // type config holds configuration
 type config struct {
-	stdout   io.Writer
-	stderr   io.Writer
+	stdin    io.Reader
+	out      printer
 	host     string
 	user     string
 	pass     string
 	insecure bool
 	debug    bool
 	quiet    bool
-	action   string
+	actions  actionList
 	get      bool
 	list     bool
 	printver bool
 	ignore   bool
 	timeout  int
+	jsonerr  bool
+	agent    string
+	shell    bool
+	client   *http.Client
+	token    string
+	muuid    string
+	mserial  string
+	check    bool
+	params   params
+	dialaddr string
+	output   string
+	cache    bool
+	refresh  bool
+	cachettl time.Duration
+	hook     requestHook
+	legacy   bool
+	listall  bool
+	retries  int
+	trace    bool
+	sysurl   string
+	wait     bool
+	waittime time.Duration
+	expand   bool
+	keepon   bool
+	target   string
+	allsys   bool
+	nook     bool
+	nocross  bool
+	vendor   string
+	http1    bool
+	sel      bool
+	selsev   string
+	selorder string
+	skipuns  bool
+	getboot  bool
+	setboot  string
+	quietok  bool
+	scheme   string
+	cfgfile  string
+	export   string
+	servname string
+	hostfile string
+	parallel int
+	allow    []string
+	deny     []string
+	ctx      context.Context
+	hosttmo  time.Duration
+	totaltmo time.Duration
+	conform  bool
+	filter   string
+	single   bool
+	events   bool
+	tmpl     *template.Template
+	report   string
+	mockaddr string
+	invurl   string
+	invauth  string
+	har      *harLog
+	dsuser   string
+	dspass   string
+	dsheader string
+	ensure   string
+	tlsinfo  bool
+	tlsonce  *sync.Once
+	getsecb  bool
+	secboot  string
+	delay    time.Duration
+	maxbody  int64
+	limiter  *rateLimiter
+	listboot bool
+	verify   bool
+	srcip    string
+	group    string
+	members  map[string]profile
+	compare  string
+	cmpacts  bool
+	rawpath  string
+	rawout   string
+	igntime  bool
+	webhook  string
+	whauth   string
+	whbatch  bool
+	listsys  bool
+	pollint  time.Duration
+	pollmax  time.Duration
+	mac      string
+	tgtstdin bool
+	warntls  bool
+	rdtype   string
+	vercreds bool
+	selsince time.Duration
+	selafter string
+	record   *fixture
+	replay   *fixture
+	info     bool
+	sess     *session
+	norelog  bool
+	pushgw   string
+	job      string
+	yes      bool
+	rawfield string
+	bootprog bool
+	cacert   string
+	caonly   bool
+	roots    *x509.CertPool
+	dumpallw bool
+	limit    int
+	sample   int
+	seed     int64
+	msgcodes messageCodes
+	bmcnet   bool
+	strict   bool
+	memo     *resourceMemo
+	waittcp  string
+	can      string
+	diff     bool
+	nodisc   bool
+	verfirst int
+	assumed  []string
+	samples  *[]hostMetrics
+	envinsec bool
 }
 
+// type requestHook is a function called after every http request, used by -trace to print requests
+type requestHook func(method, url string, status int, dur time.Duration, err error)
+
 // type system describes (partial) redfish system
 type system struct {
-	PowerState string `json:"PowerState"`
-	Actions    struct {
-		ComputerSystemReset struct {
-			ResetTypeRedfishAllowableValues []string `json:"ResetType@Redfish.AllowableValues"`
-			RedfishActionInfo               string   `json:"@Redfish.ActionInfo"`
-			Target                          string   `json:"target"`
-		} `json:"#ComputerSystem.Reset"`
+	OdataID      string `json:"@odata.id"`
+	Etag         string `json:"@odata.etag"`
+	PowerState   string `json:"PowerState"`
+	UUID         string `json:"UUID"`
+	SerialNumber string `json:"SerialNumber"`
+	SKU          string `json:"SKU"`
+	Model        string `json:"Model"`
+	BiosVersion  string `json:"BiosVersion"`
+	LogServices  struct {
+		OdataID string `json:"@odata.id"`
+	} `json:"LogServices"`
+	RelatedItem []odataLink `json:"RelatedItem"`
+	SecureBoot  odataLink   `json:"SecureBoot"`
+	Links       struct {
+		RelatedItem []odataLink `json:"RelatedItem"`
+		Chassis     []odataLink `json:"Chassis"`
+	} `json:"Links"`
+	Boot struct {
+		BootOrder   []string `json:"BootOrder"`
+		BootOptions struct {
+			OdataID string `json:"@odata.id"`
+		} `json:"BootOptions"`
+	} `json:"Boot"`
+	Actions struct {
+		ComputerSystemReset resetAction                `json:"#ComputerSystem.Reset"`
+		Oem                 map[string]json.RawMessage `json:"Oem"`
 	} `json:"Actions"`
+	ProcessorSummary struct {
+		Count int `json:"Count"`
+	} `json:"ProcessorSummary"`
+	MemorySummary struct {
+		TotalSystemMemoryGiB float64 `json:"TotalSystemMemoryGiB"`
+	} `json:"MemorySummary"`
+	BootProgress struct {
+		LastState     string `json:"LastState"`
+		LastStateTime string `json:"LastStateTime"`
+		OemLastState  string `json:"OemLastState"`
+	} `json:"BootProgress"`
+}
+
+// type odataLink describes link to another redfish resource
+type odataLink struct {
+	OdataID string `json:"@odata.id"`
+}
+
+// type resetAction describes redfish reset action of the system, chassis or manager
+type resetAction struct {
+	ResetTypeRedfishAllowableValues []string `json:"ResetType@Redfish.AllowableValues"`
+	RedfishActionInfo               string   `json:"@Redfish.ActionInfo"`
+	Target                          string   `json:"target"`
+}
+
+// standard redfish reset types
+var resetTypes = []string{"On", "ForceOff", "GracefulShutdown", "GracefulRestart", "ForceRestart", "Nmi", "ForceOn", "PushPowerButton", "PowerCycle", "Suspend", "Pause", "Resume"}
+
+// type actionInfo describes power action with its destructiveness and human readable description
+type actionInfo struct {
+	Action      string `json:"action"`
+	Destructive bool   `json:"destructive"`
+	Description string `json:"description,omitempty"`
+}
+
+// attributes of standard redfish reset types, actions interrupting running system are destructive
+var resetTypeInfo = map[string]actionInfo{
+	"On":               {Destructive: false, Description: "Turn on the unit"},
+	"ForceOff":         {Destructive: true, Description: "Immediately remove power"},
+	"GracefulShutdown": {Destructive: true, Description: "Shut down gracefully and power off"},
+	"GracefulRestart":  {Destructive: true, Description: "Shut down gracefully and restart"},
+	"ForceRestart":     {Destructive: true, Description: "Shut down immediately and non-gracefully and restart"},
+	"Nmi":              {Destructive: true, Description: "Generate a diagnostic interrupt, usually an NMI on x86 systems"},
+	"ForceOn":          {Destructive: false, Description: "Turn on the unit immediately"},
+	"PushPowerButton":  {Destructive: true, Description: "Simulate the pressing of the physical power button"},
+	"PowerCycle":       {Destructive: true, Description: "Power off and then power on the unit"},
+	"Suspend":          {Destructive: true, Description: "Write the state of the unit to disk before powering off"},
+	"Pause":            {Destructive: true, Description: "Pause execution on the unit but do not remove power"},
+	"Resume":           {Destructive: false, Description: "Resume execution on the paused unit"},
+}
+
+// describeAction returns attributes of the action, unknown and OEM actions are conservatively considered destructive
+func describeAction(action string) actionInfo {
+	info, ok := resetTypeInfo[action]
+	if !ok {
+		info.Destructive = true
+	}
+	info.Action = action
+	return info
+}
+
+// type actionList holds power actions set with repeatable -action flag or as comma separated list
+type actionList []string
+
+// String returns actions as comma separated list
+func (a *actionList) String() string {
+	return strings.Join(*a, ",")
+}
+
+// Set appends action or comma separated list of actions
+func (a *actionList) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return fmt.Errorf("empty action")
+		}
+		*a = append(*a, v)
+	}
+	return nil
+}
+
+// type messageCodes maps redfish MessageIds to process exit codes set with repeatable -map-message flag
+type messageCodes map[string]int
+
+// String returns mappings in MessageId=code format
+func (m messageCodes) String() string {
+	var kv []string
+	for k, v := range m {
+		kv = append(kv, fmt.Sprintf("%s=%d", k, v))
+	}
+	sort.Strings(kv)
+	return strings.Join(kv, ",")
+}
+
+// Set parses mapping in MessageId=code format, code must be between 1 and 125
+func (m messageCodes) Set(s string) error {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return fmt.Errorf("mapping must be in MessageId=code format")
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+	if err != nil || code < 1 || code > 125 {
+		return fmt.Errorf("exit code must be a number between 1 and 125")
+	}
+	m[strings.TrimSpace(kv[0])] = code
+	return nil
+}
+
+// exitCode returns exit code mapped to MessageId of redfish error
+// MessageId without version (like Base.ActionNotSupported) matches any version of the registry
+func (m messageCodes) exitCode(err error) (int, bool) {
+	var rerr *redfishError
+	if len(m) == 0 || !errors.As(err, &rerr) || rerr.messageID == "" {
+		return 0, false
+	}
+	parts := strings.Split(rerr.messageID, ".")
+	unversioned := parts[0] + "." + parts[len(parts)-1]
+	for id, code := range m {
+		if strings.EqualFold(id, rerr.messageID) || strings.EqualFold(id, unversioned) {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// type params holds additional action parameters set with repeatable -param flag
+type params map[string]interface{}
+
+// String returns parameters in key=value format
+func (p params) String() string {
+	var kv []string
+	for k, v := range p {
+		kv = append(kv, fmt.Sprintf("%s=%v", k, v))
+	}
+	return strings.Join(kv, ",")
+}
+
+// Set parses parameter in key=value format, inferring boolean and numeric value types
+func (p params) Set(s string) error {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return fmt.Errorf("parameter must be in key=value format")
+	}
+	if i, err := strconv.ParseInt(kv[1], 10, 64); err == nil {
+		p[kv[0]] = i
+	} else if f, err := strconv.ParseFloat(kv[1], 64); err == nil {
+		p[kv[0]] = f
+	} else if kv[1] == "true" || kv[1] == "false" {
+		p[kv[0]] = kv[1] == "true"
+	} else {
+		p[kv[0]] = kv[1]
+	}
+	return nil
+}
+
+// type actionOutcome describes how certainly the action took effect
+type actionOutcome string
+
+// outcomes of performed action, ambiguous action was accepted with empty response, so its effect is not confirmed
+const (
+	outcomeSuccess         actionOutcome = "Success"
+	outcomeAccepted        actionOutcome = "Accepted"
+	outcomeIgnoredConflict actionOutcome = "IgnoredConflict"
+	outcomeAmbiguous       actionOutcome = "Ambiguous"
+	outcomeFailed          actionOutcome = "Failed"
+)
+
+// type actionResult describes result of performed action
+type actionResult struct {
+	Action  string        `json:"action"`
+	Outcome actionOutcome `json:"outcome"`
+	Status  int           `json:"status,omitempty"`
+	Async   bool          `json:"async"`
+	TaskURL string        `json:"taskUrl,omitempty"`
+	TaskID  string        `json:"taskId,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// type redfishError describes unexpected http response status with optional redfish extended error information
+type redfishError struct {
+	expected   string
+	statusCode int
+	messageID  string
+	message    string
+	hint       string
+}
+
+// Error returns error message including decoded redfish message if available
+func (e *redfishError) Error() string {
+	msg := fmt.Sprintf("wrong response status code - expected: %s, got: %d (%s)", e.expected, e.statusCode, http.StatusText(e.statusCode))
+	if e.hint != "" {
+		msg = e.hint
+	}
+	if e.messageID != "" || e.message != "" {
+		msg = fmt.Sprintf("%s - %s: %s", msg, e.messageID, e.message)
+	}
+	return msg
+}
+
+// exit code returned when requested action is not supported on the host and -skip-unsupported is set
+// or when action checked with -can is not supported
+const exitUnsupported = 3
+
+// exit code returned when the host provides valid but empty systems collection, like headless node
+const exitNoSystems = 5
+
+// exit code returned when the host does not provide redfish systems collection at all
+const exitNotRedfish = 6
+
+// errUnsupported is returned when the host does not provide requested reset action
+var errUnsupported = errors.New("action not supported on this host")
+
+// type exitError describes error which should result in specific process exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+// Error returns message of wrapped error
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns wrapped error
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// type quietError describes error reported by exit code only, like negative answer of -can
+type quietError struct {
+	err error
+}
+
+// Error returns message of wrapped error
+func (e *quietError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns wrapped error
+func (e *quietError) Unwrap() error {
+	return e.err
 }
 
 // main function
 func main() {
-	if err := run(os.Args, os.Stdout, os.Stderr); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+	if err := run(os.Args, os.Stdin, os.Stdout, os.Stderr); err != nil {
+		var qerr *quietError
+		if !errors.As(err, &qerr) {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		}
+		var eerr *exitError
+		if errors.As(err, &eerr) {
+			os.Exit(eerr.code)
+		}
 		os.Exit(1)
 	}
 }
 
-// run parses passed arguments, builds config and runs specified function: get, list or action
-func run(args []string, stdout io.Writer, stderr io.Writer) error {
+// run parses passed arguments, builds config and runs specified function: get, list, list-all, action, shell, check, sel, get-bootorder, set-bootorder, export-profile, conformance, subscribe, ensure, get-secureboot, secureboot, list-boot, compare, raw, list-systems, verify-creds, info, boot-progress, bmc-net or can
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) (err error) {
 	var c config
-	c.stdout = stdout
-	c.stderr = stderr
+	var pjson, passfile, profname string
+	var insecureok, actionstdin bool
+	var allow, deny actionList
+	var tmpltext, harfile, recordfile, replayfile string
+	var printcfg, failfast bool
+	var rate float64
+	var grouphosts []string
+	c.params = params{}
+	c.msgcodes = messageCodes{}
+	c.stdin = stdin
+	c.out = &streamPrinter{stdout, stderr}
 
 	// init and parse flags
 	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
 	flags.SetOutput(stderr)
 	flags.BoolVar(&c.get, "get", false, "get current power state")
+	flags.BoolVar(&c.bootprog, "boot-progress", false, "print last boot progress state of the system (like OSBootStarted) with time it was reached")
+	flags.StringVar(&c.can, "can", "", "exit with code 0 if the system supports `action`, 3 if it does not and 1 if the check failed")
+	flags.BoolVar(&c.bmcnet, "bmc-net", false, "print MAC address, IPv4 addresses and host name of every network interface of the manager (BMC)")
+	flags.BoolVar(&c.info, "info", false, "print power state with model, serial number, processor count, memory size and BIOS version of the system")
 	flags.BoolVar(&c.list, "list", false, "list supported power actions")
-	flags.StringVar(&c.action, "action", "", "power action to perform")
+	flags.BoolVar(&c.vercreds, "verify-creds", false, "only verify that the host accepts credentials (for example after credential rotation), no other operation is performed")
+	flags.BoolVar(&c.listsys, "list-systems", false, "list members of systems collection without selecting one (names and ids with -expand)")
+	flags.BoolVar(&c.listall, "list-all", false, "list reset actions of all systems, chassis and managers")
+	flags.Var(&c.actions, "action", "power `action` to perform (can be repeated or comma separated to perform actions in sequence)")
+	flags.Var(&allow, "allow-actions", "comma separated list of `actions` allowed by local policy (or set REDPOWER_ALLOW_ACTIONS)")
+	flags.Var(&deny, "deny-actions", "comma separated list of `actions` denied by local policy, takes precedence over allowed actions (or set REDPOWER_DENY_ACTIONS)")
+	flags.StringVar(&c.ensure, "ensure", "", "ensure desired power `state` (On or Off), performing action only if current power state differs")
+	flags.BoolVar(&actionstdin, "action-stdin", false, "read power action from the first line of standard input instead of -action")
+	flags.StringVar(&c.cfgfile, "config", "", "configuration file with host profiles (default config.json in redpower user config directory)")
+	flags.StringVar(&profname, "profile", "", "use host settings from named `profile` of the configuration file")
+	flags.StringVar(&c.export, "export-profile", "", "discover host and save its settings as named `profile` in the configuration file")
 	flags.StringVar(&c.host, "host", "", "BMC address and optional port (host or host:port)")
+	flags.StringVar(&c.scheme, "scheme", "https", "URL scheme used to connect to BMC: https or http (TLS options are ignored with http)")
+	flags.StringVar(&c.mac, "mac", "", "use BMC with MAC `address` found in local ARP table instead of -host (Linux only)")
+	flags.StringVar(&c.invurl, "inventory-url", "", "resolve logical -host name to BMC address and credentials by getting `url`/name from inventory service")
+	flags.StringVar(&c.invauth, "inventory-auth", os.Getenv("REDPOWER_INVENTORY_AUTH"), "Authorization header `value` sent to inventory service (or set REDPOWER_INVENTORY_AUTH)")
+	flags.StringVar(&c.webhook, "webhook", "", "post json result of actions to `url` after they complete or fail")
+	flags.StringVar(&c.whauth, "webhook-auth", os.Getenv("REDPOWER_WEBHOOK_AUTH"), "Authorization header `value` sent to -webhook (or set REDPOWER_WEBHOOK_AUTH)")
+	flags.BoolVar(&c.whbatch, "webhook-batch", false, "post single json report of all hosts to -webhook instead of result of every host (with -hosts, -group or -targets-stdin)")
+	flags.StringVar(&c.hostfile, "hosts", "", "run for every host listed in `file` (one per line) instead of -host")
+	flags.BoolVar(&c.tgtstdin, "targets-stdin", false, "run for every host<TAB>system path line (like 10.0.0.1\t/redfish/v1/Systems/1) read from standard input instead of -host, without system discovery")
+	flags.StringVar(&c.group, "group", "", "run for every host profile of named `group` of the configuration file instead of -host")
+	flags.StringVar(&c.report, "report", "", "write json report of outcome of every host to `file` (with -hosts)")
+	flags.Float64Var(&rate, "rate", 0, "maximum number of requests per second of the whole run (shared by all hosts with -hosts), 0 means no limit")
+	flags.IntVar(&c.limit, "limit", 0, "process only first `N` hosts (with -hosts, -group or -targets-stdin), 0 means all")
+	flags.IntVar(&c.sample, "sample", 0, "process only random sample of `N` hosts (with -hosts, -group or -targets-stdin), 0 means all")
+	flags.Int64Var(&c.seed, "seed", 0, "seed of -sample for reproducible sampling, 0 means random")
+	flags.BoolVar(&c.nodisc, "no-discovery", false, "resolve system URL and allowed actions on first hosts only and assume them for all other hosts (with -hosts, for fleets of identical models only)")
+	flags.IntVar(&c.verfirst, "verify-first", 1, "number of first hosts which must resolve the same system URL and allowed actions before they are assumed (with -no-discovery)")
+	flags.IntVar(&c.parallel, "parallel", 10, "number of hosts processed in parallel (with -hosts)")
 	flags.StringVar(&c.user, "user", "", "BMC username")
 	flags.StringVar(&c.pass, "pass", "", "BMC password")
-	flags.BoolVar(&c.insecure, "insecure", false, "do not verify host certificate")
-	flags.BoolVar(&c.debug, "debug", false, "enable printing of http response body")
+	flags.StringVar(&c.dsuser, "downstream-user", "", "username of downstream BMC behind redfish aggregator")
+	flags.StringVar(&c.dspass, "downstream-pass", "", "password of downstream BMC behind redfish aggregator")
+	flags.StringVar(&c.dsheader, "downstream-header", "X-Auth-Downstream", "`header` carrying basic auth encoded downstream credentials")
+	flags.StringVar(&c.token, "token", os.Getenv("REDPOWER_TOKEN"), "use existing redfish session `token` instead of -user and -pass, session is not deleted, -user and -pass are only used to create new session if the token expires (or set REDPOWER_TOKEN)")
+	flags.BoolVar(&c.norelog, "no-relogin", false, "do not create new session with -user and -pass when session token is rejected with 401 (Unauthorized)")
+	flags.StringVar(&passfile, "pass-file", "", "read BMC password from file")
+	flags.BoolVar(&c.insecure, "insecure", false, "do not verify host certificate (or set REDPOWER_INSECURE=1 in lab environment, overridden by -insecure=false, -cacert, -servername and -ignore-cert-time)")
+	flags.BoolVar(&c.warntls, "warn-on-insecure-default", true, "warn before connecting to IP address with certificate verification enabled and no TLS options, as it will probably fail")
+	flags.BoolVar(&c.igntime, "ignore-cert-time", false, "verify host certificate ignoring its validity dates (for BMCs with wrong clock), narrower alternative to -insecure")
+	flags.StringVar(&c.cacert, "cacert", "", "add CA certificates from PEM `file` to system trust store for verification of host certificate")
+	flags.BoolVar(&c.caonly, "cacert-only", false, "trust only -cacert certificates instead of adding them to system trust store")
+	flags.StringVar(&c.servname, "servername", "", "verify host certificate against this `name` instead of -host (for BMCs addressed by IP)")
+	flags.BoolVar(&c.tlsinfo, "tls-info", false, "print negotiated TLS version, cipher suite and host certificate (also traced with -trace)")
+	flags.BoolVar(&insecureok, "i-know-this-is-insecure", false, "confirm -insecure in non-interactive use (or set REDPOWER_ALLOW_INSECURE=1)")
+	flags.BoolVar(&c.debug, "debug", false, "enable printing of http requests and response bodies, credentials are redacted")
+	flags.StringVar(&recordfile, "record", "", "record all http responses to fixture `file` for offline testing with -replay, credentials are redacted")
+	flags.StringVar(&replayfile, "replay", "", "serve http responses from fixture `file` recorded with -record instead of connecting to the host")
+	flags.StringVar(&harfile, "har", "", "record all http requests and responses to `file` in HAR format for vendor support, credentials are redacted")
+	flags.BoolVar(&c.trace, "trace", false, "print discovery steps and requests with their status codes")
 	flags.BoolVar(&c.quiet, "quiet", false, "do not output any messages except errors")
+	flags.BoolVar(&c.quietok, "quiet-on-success", false, "do not output anything unless operation fails")
+	flags.StringVar(&c.mockaddr, "serve-mock", "", "serve minimal mock redfish service on `address` (like :8443) for testing, accepting only -user and -pass if set")
+	flags.BoolVar(&printcfg, "print-config", false, "print effective configuration with source of every value and quit, secrets are redacted")
 	flags.BoolVar(&c.printver, "version", false, "print program version and quit")
+	flags.BoolVar(&c.yes, "yes", false, "do not ask for confirmation before performing destructive action on multiple hosts (with -hosts or -group on terminal)")
 	flags.BoolVar(&c.ignore, "ignore", false, "ignore conflicts (like power on the server which is already on)")
-	flags.IntVar(&c.timeout, "timeout", 30, "operation timeout in seconds")
+	flags.Int64Var(&c.maxbody, "max-response-size", defaultMaxBody, "maximum size of response body in `bytes`, larger responses are rejected")
+	flags.IntVar(&c.timeout, "timeout", 30, "timeout of every single http request in seconds (retries and -wait are bounded by -host-timeout)")
+	flags.DurationVar(&c.hosttmo, "host-timeout", 0, "maximum time spent on single host including retries and waiting, 0 means no limit")
+	flags.DurationVar(&c.totaltmo, "total-timeout", 0, "maximum time of the whole run (useful with -hosts), 0 means no limit")
+	flags.IntVar(&c.retries, "retries", 0, "number of retries of requests failed with transient errors")
+	flags.BoolVar(&c.jsonerr, "json-errors", false, "print errors in json format to standard output")
+	flags.StringVar(&c.agent, "user-agent", defaultUserAgent(), "User-Agent header sent with requests")
+	flags.BoolVar(&c.shell, "shell", false, "start interactive shell using single session")
+	flags.StringVar(&c.sysurl, "system-url", "", "path of the system (like /redfish/v1/Systems/1) to use instead of discovery")
+	flags.BoolVar(&c.single, "assume-single", false, "select first member of systems collection without verifying the number of systems (workaround for broken firmware)")
+	flags.BoolVar(&c.expand, "expand", false, "request expanded systems collection ($expand) to avoid fetching every member")
+	flags.StringVar(&c.muuid, "match-uuid", "", "select system with specified UUID")
+	flags.StringVar(&c.mserial, "match-serial", "", "select system with specified serial number or SKU (service tag)")
+	flags.StringVar(&c.filter, "filter", "", "select system with property matching `path=value` (dot notation, like Oem.Tags=gpu)")
+	flags.StringVar(&c.compare, "compare", "", "compare power state with other `host` (using the same credentials) or snapshot file saved with -output json, fails if they differ")
+	flags.BoolVar(&c.cmpacts, "compare-actions", false, "compare allowed actions as well (with -compare)")
+	flags.StringVar(&c.rawpath, "raw", "", "print response body of GET request for redfish `path` (like /redfish/v1/Systems)")
+	flags.StringVar(&c.rawfield, "raw-field", "", "print only value of property at `path` of -raw response (dot notation with [index], like Members[0].@odata.id)")
+	flags.StringVar(&c.rawout, "raw-out", "", "stream response body of -raw request to `file` instead of printing it")
+	flags.BoolVar(&c.check, "check", false, "check credentials and system discovery without performing any action")
+	flags.BoolVar(&c.conform, "conformance", false, "verify that host conforms to redfish requirements of power control and print checklist")
+	flags.Var(c.params, "param", "additional action `parameter` in key=value format (can be repeated)")
+	flags.StringVar(&pjson, "param-json", "", "additional action parameters as json object")
+	flags.StringVar(&c.dialaddr, "dial-addr", "", "connect to this address (host:port or unix:/path/to/socket) instead of -host")
+	flags.StringVar(&c.target, "target", "system", "resource to operate on: system, chassis (-get only) or manager (-action ResetToDefaults only)")
+	flags.StringVar(&c.rdtype, "reset-default-type", "", "reset `type` of manager ResetToDefaults action, like ResetAll, PreserveNetworkAndUsers or PreserveNetwork")
+	flags.BoolVar(&c.allsys, "all-systems", false, "get power state of all systems contained in the chassis (with -target chassis)")
+	flags.BoolVar(&c.listboot, "list-boot", false, "list boot options with their references, display names and UEFI device paths")
+	flags.BoolVar(&c.getboot, "get-bootorder", false, "print persistent boot order")
+	flags.BoolVar(&c.getsecb, "get-secureboot", false, "print secure boot state")
+	flags.StringVar(&c.secboot, "secureboot", "", "enable or disable secure boot (on or off), combine with -action to reset the system afterwards")
+	flags.StringVar(&c.setboot, "set-bootorder", "", "set persistent boot order to comma separated list of boot option `references` (like Boot0001,Boot0002)")
+	flags.BoolVar(&c.events, "subscribe", false, "stream power related events from redfish event service (server-sent events) until interrupted")
+	flags.BoolVar(&c.sel, "sel", false, "print system event log (SEL) entries")
+	flags.StringVar(&c.selsev, "sel-severity", "ok", "print only SEL entries with this or higher severity: ok, warning or critical")
+	flags.StringVar(&c.selorder, "sel-order", "asc", "order of SEL entries by creation time: asc or desc")
+	flags.DurationVar(&c.selsince, "sel-since", 0, "print only SEL entries created within `duration` before now (like 1h or 24h)")
+	flags.StringVar(&c.selafter, "sel-after", "", "print only SEL entries created after `time` in RFC3339 format (like 2024-01-02T15:04:05Z)")
+	flags.StringVar(&c.vendor, "vendor", "generic", "vendor hint for OEM reset actions: dell, hpe, lenovo or generic")
+	flags.StringVar(&c.pushgw, "pushgateway", "", "push power state metrics of every host to prometheus pushgateway at `url` (-get only)")
+	flags.StringVar(&c.job, "job", "redpower", "job `name` used in grouping key of metrics pushed to -pushgateway")
+	flags.StringVar(&c.output, "output", "text", "output format: text, json, yaml, template, csv or prometheus (-get only)")
+	flags.StringVar(&tmpltext, "template", "", "go text/template rendering result with -output template, like '{{.Host}} {{.PowerState}}'")
+	flags.BoolVar(&c.cache, "cache", false, "cache resolved system URL on disk for subsequent runs")
+	flags.BoolVar(&c.refresh, "refresh", false, "ignore cached system URL and resolve it again (with -cache)")
+	flags.DurationVar(&c.cachettl, "cache-ttl", time.Hour, "how long cached system URL is valid")
+	flags.DurationVar(&c.delay, "delay", 0, "wait for `duration` after discovery before performing action (to stagger hosts with -hosts)")
+	flags.BoolVar(&c.wait, "wait", false, "wait until action results in expected power state")
+	flags.BoolVar(&c.verify, "verify", false, "read power state once after action and warn if it does not match the action (lighter than -wait)")
+	flags.BoolVar(&c.diff, "diff", false, "read power state after actions (settled with -wait) and print it together with state before them, like PowerState: Off -> On")
+	flags.StringVar(&c.waittcp, "wait-for-tcp", "", "after actions wait until `host:port` (like SSH port of the system) accepts TCP connections, up to -wait-timeout")
+	flags.DurationVar(&c.waittime, "wait-timeout", 5*time.Minute, "maximum time to wait for expected power state (with -wait)")
+	flags.DurationVar(&c.pollint, "poll-interval", time.Second, "initial interval between power state checks (with -wait), doubled after every check up to -poll-max-interval")
+	flags.DurationVar(&c.pollmax, "poll-max-interval", 5*time.Second, "maximum interval between power state checks (with -wait)")
+	flags.BoolVar(&c.nook, "no-ok", false, "do not print OK line after performed action")
+	flags.Var(c.msgcodes, "map-message", "exit with `code` when BMC response carries redfish MessageId, in MessageId=code format (can be repeated)")
+	flags.BoolVar(&c.strict, "strict-json", false, "reject system resource with fields unknown to redpower or without fields required for power control (for firmware QA)")
+	flags.BoolVar(&c.dumpallw, "dump-allowable-on-error", false, "print allowable reset types and power state of the system read again after action rejected with 400 or 405")
+	flags.BoolVar(&c.keepon, "continue-on-error", false, "continue performing action sequence after failed action")
+	flags.BoolVar(&c.keepon, "keep-going", false, "continue performing action sequence after failed action (same as -continue-on-error)")
+	flags.BoolVar(&failfast, "fail-fast", false, "stop action sequence on first failed action (default, conflicts with -keep-going)")
+	flags.BoolVar(&c.skipuns, "skip-unsupported", false, "skip actions not supported by the host and exit with code 3 instead of failing")
+	flags.BoolVar(&c.nocross, "no-follow-cross-host", false, "refuse to follow redirects to other hosts")
+	flags.StringVar(&c.srcip, "source-ip", "", "local IP `address` used for connections to BMC (on hosts with multiple interfaces)")
+	flags.BoolVar(&c.http1, "http1", false, "force HTTP/1.1 (for BMCs with broken HTTP/2 support)")
+	flags.BoolVar(&c.legacy, "tls-legacy", false, "allow TLS renegotiation, old TLS versions and legacy cipher suites (insecure, for old BMC firmware)")
 	if err := flags.Parse(args[1:]); err != nil {
 		return err
 	}
+	sources := configSources{}
+	flags.Visit(func(f *flag.Flag) {
+		sources[f.Name] = "command line"
+	})
+
+	// report errors in json format if requested
+	defer func() {
+		var qerr *quietError
+		if err != nil && c.jsonerr && !errors.As(err, &qerr) {
+			printJSONError(c, err)
+		}
+	}()
+
+	// apply profile settings not overridden on command line
+	if profname != "" {
+		path, err := configPath(c.cfgfile)
+		if err != nil {
+			return err
+		}
+		if err := applyProfile(flags, path, profname); err != nil {
+			return err
+		}
+		flags.Visit(func(f *flag.Flag) {
+			if sources[f.Name] == "" {
+				sources[f.Name] = "profile " + profname
+			}
+		})
+	}
+
+	// lab default from environment gives way to -insecure and any certificate verification flag set explicitly
+	if os.Getenv("REDPOWER_INSECURE") == "1" && sources["insecure"] == "" && sources["cacert"] == "" && sources["cacert-only"] == "" && sources["servername"] == "" && sources["ignore-cert-time"] == "" {
+		c.insecure, c.envinsec = true, true
+		sources["insecure"] = "environment REDPOWER_INSECURE"
+	}
+
+	// resolve profiles of group members, they are processed like hosts of -hosts file
+	if c.group != "" {
+		if grouphosts, c.members, err = groupMembers(c, sources); err != nil {
+			return err
+		}
+	}
+
+	// read password from file
+	if passfile != "" {
+		if c.pass != "" {
+			return fmt.Errorf("arguments -pass and -pass-file cannot be used at the same time")
+		}
+		b, err := ioutil.ReadFile(passfile)
+		if err != nil {
+			return fmt.Errorf("cannot read password file: %s", err)
+		}
+		c.pass = strings.TrimRight(string(b), "\r\n")
+		if c.pass == "" {
+			return fmt.Errorf("password file %s is empty", passfile)
+		}
+		sources["pass"] = "pass-file " + passfile
+	}
+
+	// resolve BMC address from MAC address using local ARP table
+	if c.mac != "" {
+		if c.host != "" || batchMode(c) || c.invurl != "" {
+			return fmt.Errorf("argument -mac cannot be used with -host, -hosts, -group or -inventory-url")
+		}
+		if c.host, err = resolveMAC(c.mac); err != nil {
+			return err
+		}
+		sources["host"] = "ARP table entry of " + c.mac
+	}
+
+	// resolve logical host name using inventory service, in batch mode hosts are resolved one by one
+	if c.host != "" && c.invurl != "" {
+		old := c
+		if c, err = resolveInventory(c); err != nil {
+			return err
+		}
+		sources.note(old, c, "inventory "+c.invurl)
+	}
+
+	// fall back to credentials from .netrc, in batch mode credentials are looked up for every host
+	if c.host != "" && c.token == "" && (c.user == "" || c.pass == "") {
+		old := c
+		c.user, c.pass = netrcCredentials(c.host, c.user, c.pass)
+		sources.note(old, c, ".netrc")
+	}
+
+	// print resolved configuration instead of running any function
+	if printcfg {
+		return printConfig(c, flags, sources, allow, deny)
+	}
+
+	// read action from standard input
+	if actionstdin {
+		if len(c.actions) > 0 {
+			return fmt.Errorf("arguments -action and -action-stdin cannot be used at the same time")
+		}
+		line, err := bufio.NewReader(c.stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("cannot read action from standard input: %s", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return fmt.Errorf("no action read from standard input")
+		}
+		if err := c.actions.Set(line); err != nil {
+			return err
+		}
+	}
+
+	// count requested functions
+	modes := count(c.get || c.list, len(c.actions) > 0 && c.secboot == "", c.shell, c.check, c.sel, c.listall, c.getboot, c.setboot != "", c.export != "", c.conform, c.events, c.ensure != "", c.getsecb, c.secboot != "", c.listboot, c.compare != "", c.rawpath != "", c.listsys, c.vercreds, c.info, c.bootprog, c.bmcnet, c.can != "")
 
 	// verify flags
 	switch {
@@ -90,229 +729,1623 @@ func run(args []string, stdout io.Writer, stderr io.Writer) error {
 		flags.PrintDefaults()
 		return fmt.Errorf("no arguments provided")
 	case c.printver:
-		fmt.Fprintf(stdout, "redpower  version: %s (%s) build date: %s\n", version, commit, date)
+		c.out.Result("redpower  version: %s (%s) build date: %s\n", version, commit, date)
 		return nil
-	case c.host == "":
-		return fmt.Errorf("missing -host argument")
-	case c.user == "":
+	case c.mockaddr != "":
+		return serveMock(c)
+	case c.host == "" && !batchMode(c):
+		return fmt.Errorf("missing -host, -hosts, -group or -targets-stdin argument")
+	case count(c.host != "", c.hostfile != "", c.group != "", c.tgtstdin) > 1:
+		return fmt.Errorf("arguments -host, -hosts, -group and -targets-stdin cannot be used at the same time")
+	case batchMode(c) && c.shell:
+		return fmt.Errorf("-hosts, -group and -targets-stdin cannot be used with -shell")
+	case recordfile != "" && replayfile != "":
+		return fmt.Errorf("arguments -record and -replay cannot be used at the same time")
+	case c.tgtstdin && actionstdin:
+		return fmt.Errorf("arguments -targets-stdin and -action-stdin cannot be used at the same time")
+	case c.tgtstdin && c.sysurl != "":
+		return fmt.Errorf("arguments -targets-stdin and -system-url cannot be used at the same time")
+	case c.hosttmo < 0 || c.totaltmo < 0:
+		return fmt.Errorf("-host-timeout and -total-timeout cannot be negative")
+	case c.webhook != "" && len(c.actions) == 0 && c.ensure == "":
+		return fmt.Errorf("-webhook can only be used with -action or -ensure")
+	case c.whbatch && c.webhook == "":
+		return fmt.Errorf("-webhook-batch requires -webhook")
+	case c.whbatch && !batchMode(c):
+		return fmt.Errorf("-webhook-batch can only be used with -hosts, -group or -targets-stdin")
+	case c.report != "" && !batchMode(c):
+		return fmt.Errorf("-report can only be used with -hosts, -group or -targets-stdin")
+	case c.limit < 0 || c.sample < 0:
+		return fmt.Errorf("-limit and -sample cannot be negative")
+	case c.limit > 0 && c.sample > 0:
+		return fmt.Errorf("arguments -limit and -sample cannot be used at the same time")
+	case (c.limit > 0 || c.sample > 0) && !batchMode(c):
+		return fmt.Errorf("-limit and -sample can only be used with -hosts, -group or -targets-stdin")
+	case c.seed != 0 && c.sample == 0:
+		return fmt.Errorf("-seed can only be used with -sample")
+	case c.nodisc && c.hostfile == "":
+		return fmt.Errorf("-no-discovery can only be used with -hosts")
+	case c.nodisc && (c.sysurl != "" || c.muuid != "" || c.mserial != "" || c.filter != ""):
+		return fmt.Errorf("-no-discovery cannot be used with -system-url, -match-uuid, -match-serial or -filter")
+	case c.verfirst < 1:
+		return fmt.Errorf("-verify-first must be at least 1")
+	case c.verfirst != 1 && !c.nodisc:
+		return fmt.Errorf("-verify-first can only be used with -no-discovery")
+	case c.parallel < 1:
+		return fmt.Errorf("-parallel must be at least 1")
+	case c.token != "" && c.pass != "" && c.norelog:
+		return fmt.Errorf("arguments -token and -pass (or -pass-file) cannot be used at the same time with -no-relogin")
+	case c.token != "" && batchMode(c):
+		return fmt.Errorf("-token cannot be used with -hosts, -group or -targets-stdin")
+	case c.user == "" && !batchMode(c) && c.token == "":
 		return fmt.Errorf("missing -user name")
-	case c.pass == "":
-		return fmt.Errorf("missing -password")
-	case c.action == "" && c.get == false && c.list == false:
-		return fmt.Errorf("missing -action, -get or -list argument")
-	case c.list && c.get, c.list && c.action != "", c.get && c.action != "":
-		return fmt.Errorf("arguments -action, -get and -list cannot be used at the same time")
+	case c.pass == "" && !batchMode(c) && c.token == "":
+		return fmt.Errorf("missing -pass or -pass-file argument")
+	case modes == 0:
+		return fmt.Errorf("missing -action, -get, -list, -list-all, -shell, -check, -sel, -get-bootorder, -set-bootorder, -export-profile, -conformance, -subscribe, -ensure, -get-secureboot, -secureboot, -list-boot, -compare, -raw, -list-systems, -verify-creds, -info, -boot-progress, -bmc-net or -can argument")
+	case modes > 1:
+		return fmt.Errorf("arguments -action, -get, -list, -list-all, -shell, -check, -sel, -get-bootorder, -set-bootorder, -export-profile, -conformance, -subscribe, -ensure, -get-secureboot, -secureboot, -list-boot, -compare, -raw, -list-systems, -verify-creds, -info, -boot-progress, -bmc-net and -can cannot be used at the same time")
+	case (c.dsuser == "") != (c.dspass == ""):
+		return fmt.Errorf("arguments -downstream-user and -downstream-pass must be used together")
+	case c.dsuser != "" && c.dsheader == "":
+		return fmt.Errorf("-downstream-header cannot be empty")
 	case c.quiet && c.debug:
 		return fmt.Errorf("arguments -debug and -quiet cannot be used at the same time")
+	case !contains([]string{"text", "json", "yaml", "template", "prometheus", "csv"}, c.output):
+		return fmt.Errorf("unsupported -output format: %s", c.output)
+	case c.secboot != "" && c.secboot != "on" && c.secboot != "off":
+		return fmt.Errorf("unsupported -secureboot value: %s (on or off)", c.secboot)
+	case rate < 0:
+		return fmt.Errorf("-rate cannot be negative")
+	case c.maxbody < 1:
+		return fmt.Errorf("-max-response-size must be at least 1")
+	case c.srcip != "" && net.ParseIP(c.srcip) == nil:
+		return fmt.Errorf("invalid -source-ip address: %s", c.srcip)
+	case c.srcip != "" && strings.HasPrefix(c.dialaddr, "unix:"):
+		return fmt.Errorf("-source-ip cannot be used with unix socket -dial-addr")
+	case c.rawout != "" && c.rawpath == "":
+		return fmt.Errorf("-raw-out can only be used with -raw")
+	case c.rawout != "" && batchMode(c):
+		return fmt.Errorf("-raw-out cannot be used with -hosts, -group or -targets-stdin")
+	case c.rawfield != "" && c.rawpath == "":
+		return fmt.Errorf("-raw-field can only be used with -raw")
+	case c.rawfield != "" && c.rawout != "":
+		return fmt.Errorf("arguments -raw-field and -raw-out cannot be used at the same time")
+	case c.cmpacts && c.compare == "":
+		return fmt.Errorf("-compare-actions can only be used with -compare")
+	case c.caonly && c.cacert == "":
+		return fmt.Errorf("-cacert-only requires -cacert")
+	case c.cacert != "" && c.insecure:
+		return fmt.Errorf("arguments -cacert and -insecure cannot be used at the same time")
+	case failfast && c.keepon:
+		return fmt.Errorf("arguments -fail-fast and -keep-going (or -continue-on-error) cannot be used at the same time")
+	case c.waittcp != "" && len(c.actions) == 0 && c.ensure == "":
+		return fmt.Errorf("-wait-for-tcp can only be used with -action or -ensure")
+	case c.waittcp != "" && batchMode(c):
+		return fmt.Errorf("-wait-for-tcp cannot be used with -hosts, -group or -targets-stdin")
+	case c.waittcp != "" && !validHostPort(c.waittcp):
+		return fmt.Errorf("-wait-for-tcp must be in host:port format")
+	case c.diff && len(c.actions) == 0:
+		return fmt.Errorf("-diff can only be used with -action")
+	case c.verify && c.wait:
+		return fmt.Errorf("arguments -verify and -wait cannot be used at the same time")
+	case c.verify && len(c.actions) == 0 && c.ensure == "":
+		return fmt.Errorf("-verify can only be used with -action or -ensure")
+	case c.delay < 0:
+		return fmt.Errorf("-delay cannot be negative")
+	case c.delay > 0 && len(c.actions) == 0 && c.ensure == "":
+		return fmt.Errorf("-delay can only be used with -action or -ensure")
+	case c.ensure != "" && c.ensure != "On" && c.ensure != "Off":
+		return fmt.Errorf("unsupported -ensure state: %s (On or Off)", c.ensure)
+	case c.output == "csv" && !c.get && len(c.actions) == 0 && c.ensure == "":
+		return fmt.Errorf("-output csv can only be used with -get, -action or -ensure")
+	case structured(c) && !c.get && !c.list && len(c.actions) == 0 && c.ensure == "" && !c.listsys && !c.info && !c.bootprog:
+		return fmt.Errorf("-output %s can only be used with -get, -list, -action, -ensure, -list-systems, -info or -boot-progress", c.output)
+	case c.output == "template" && tmpltext == "":
+		return fmt.Errorf("-output template requires -template argument")
+	case c.output != "template" && tmpltext != "":
+		return fmt.Errorf("-template can only be used with -output template")
+	case c.get && c.list && (c.output == "prometheus" || c.output == "csv"):
+		return fmt.Errorf("-get and -list together cannot be used with -output %s", c.output)
+	case c.get && c.list && (c.target != "system" || c.pushgw != ""):
+		return fmt.Errorf("-get and -list together cannot be used with -target %s or -pushgateway", c.target)
+	case c.output == "prometheus" && !c.get:
+		return fmt.Errorf("-output prometheus can only be used with -get")
+	case c.pushgw != "" && !c.get:
+		return fmt.Errorf("-pushgateway can only be used with -get")
+	case c.pushgw != "" && c.target == "chassis":
+		return fmt.Errorf("-pushgateway cannot be used with -target chassis")
+	case c.pushgw != "" && c.job == "":
+		return fmt.Errorf("-job cannot be empty")
+	case c.pollint <= 0:
+		return fmt.Errorf("-poll-interval must be greater than 0")
+	case c.pollmax < c.pollint:
+		return fmt.Errorf("-poll-max-interval cannot be shorter than -poll-interval")
+	case c.target != "system" && c.target != "chassis" && c.target != "manager":
+		return fmt.Errorf("unsupported -target: %s", c.target)
+	case c.target == "manager" && (len(c.actions) != 1 || !strings.EqualFold(c.actions[0], "ResetToDefaults")):
+		return fmt.Errorf("-target manager can only be used with -action ResetToDefaults")
+	case c.target == "manager" && c.rdtype == "":
+		return fmt.Errorf("missing -reset-default-type argument (like ResetAll or PreserveNetworkAndUsers)")
+	case c.target == "manager" && (c.wait || c.verify || c.output != "text" || c.secboot != ""):
+		return fmt.Errorf("-target manager cannot be used with -wait, -verify, -output or -secureboot")
+	case c.rdtype != "" && c.target != "manager":
+		return fmt.Errorf("-reset-default-type can only be used with -target manager")
+	case c.target == "chassis" && !c.get:
+		return fmt.Errorf("-target chassis can only be used with -get")
+	case c.target == "chassis" && c.output != "text":
+		return fmt.Errorf("-target chassis can only be used with -output text")
+	case !contains([]string{"dell", "hpe", "lenovo", "generic"}, c.vendor):
+		return fmt.Errorf("unsupported -vendor: %s", c.vendor)
+	case severityLevel(c.selsev) < 0:
+		return fmt.Errorf("unsupported -sel-severity: %s", c.selsev)
+	case c.selorder != "asc" && c.selorder != "desc":
+		return fmt.Errorf("unsupported -sel-order: %s", c.selorder)
+	case c.selsince < 0:
+		return fmt.Errorf("-sel-since cannot be negative")
+	case c.selsince > 0 && c.selafter != "":
+		return fmt.Errorf("arguments -sel-since and -sel-after cannot be used at the same time")
+	case (c.selsince > 0 || c.selafter != "") && !c.sel:
+		return fmt.Errorf("-sel-since and -sel-after can only be used with -sel")
+	case c.sysurl != "" && !strings.HasPrefix(c.sysurl, "/"):
+		return fmt.Errorf("-system-url must start with /")
+	case c.sysurl != "" && (c.muuid != "" || c.mserial != "" || c.filter != ""):
+		return fmt.Errorf("-system-url cannot be used with -match-uuid, -match-serial or -filter")
+	case c.single && (c.sysurl != "" || c.muuid != "" || c.mserial != "" || c.filter != ""):
+		return fmt.Errorf("-assume-single cannot be used with -system-url, -match-uuid, -match-serial or -filter")
+	case c.filter != "" && !strings.Contains(c.filter, "="):
+		return fmt.Errorf("-filter must be in property=value format")
+	case c.allsys && c.target != "chassis":
+		return fmt.Errorf("-all-systems can only be used with -target chassis")
+	case c.scheme != "https" && c.scheme != "http":
+		return fmt.Errorf("unsupported -scheme: %s", c.scheme)
+	case c.igntime && c.insecure:
+		return fmt.Errorf("arguments -ignore-cert-time and -insecure cannot be used at the same time")
+	case c.servname != "" && c.insecure:
+		return fmt.Errorf("arguments -servername and -insecure cannot be used at the same time")
+	case c.tlsinfo && c.scheme == "http":
+		return fmt.Errorf("-tls-info cannot be used with -scheme http")
+	case c.tlsinfo && structured(c):
+		return fmt.Errorf("-tls-info can only be used with -output text")
+	case c.quietok && c.shell:
+		return fmt.Errorf("-quiet-on-success cannot be used with -shell")
+	case c.quietok && c.events:
+		return fmt.Errorf("-quiet-on-success cannot be used with -subscribe")
+	}
+
+	// enforce local action policy before any request is sent, shell actions are verified when performed
+	if c.allow, c.deny, err = resolvePolicy(c, allow, deny); err != nil {
+		return err
+	}
+	if err := checkPolicy(c); err != nil {
+		return err
+	}
+
+	// hold back all output until the result is known, it is discarded on success
+	// in batch mode output is held back for every host separately
+	if c.quietok && !batchMode(c) {
+		out := c.out
+		rec := &recorder{}
+		c.out = rec
+		defer func() {
+			c.out = out
+			if err != nil {
+				rec.replay(c.out)
+			}
+		}()
+	}
+
+	// parse template before any request is sent
+	if tmpltext != "" {
+		if c.tmpl, err = template.New("output").Parse(tmpltext); err != nil {
+			return fmt.Errorf("invalid -template: %s", err)
+		}
+	}
+
+	// structured output replaces all informational messages
+	if structured(c) {
+		c.quiet = true
+		c.jsonerr = c.jsonerr || c.output == "json"
+	}
+
+	// tls options are irrelevant for plain http, but credentials are sent in clear text
+	if c.scheme == "http" {
+		c.insecure, c.legacy, c.servname, c.igntime = false, false, "", false
+		if !c.quiet {
+			c.out.Error("WARNING: -scheme http is set - credentials are sent in clear text\n")
+		}
+	}
+
+	// guard against -insecure lingering in scripts, also when it is enabled in environment
+	if c.insecure || insecureMember(c.members) {
+		if !insecureok && !isTerminal(c.stdin) && os.Getenv("REDPOWER_ALLOW_INSECURE") != "1" {
+			return fmt.Errorf("-insecure in non-interactive use requires -i-know-this-is-insecure or REDPOWER_ALLOW_INSECURE=1")
+		}
+		// lab convenience enabled in environment is always reported, even with -quiet
+		switch {
+		case c.envinsec:
+			c.out.Error("WARNING: -insecure is enabled by REDPOWER_INSECURE=1 environment variable - host certificate will NOT be verified (intended for lab use only)\n")
+		case !c.quiet:
+			c.out.Error("WARNING: -insecure is set - host certificate will NOT be verified\n")
+		}
+	}
+
+	if c.igntime && !c.quiet {
+		c.out.Error("WARNING: -ignore-cert-time is set - validity dates of host certificate will NOT be verified\n")
+	}
+
+	// certificates of BMCs reached by IP address are usually self-signed or issued for a name only
+	if c.warntls && !c.quiet && replayfile == "" && c.scheme == "https" && !c.insecure && !c.igntime && c.servname == "" && c.cacert == "" && net.ParseIP(hostName(c.host)) != nil {
+		c.out.Error("warning: host %s is an IP address - BMC certificates are usually self-signed or issued for a name, so verification will probably fail; use -servername NAME for certificate issued for a name or -insecure for self-signed certificate (disable this warning with -warn-on-insecure-default=false)\n", c.host)
+	}
+
+	// merge json action parameters, -param values take precedence
+	if pjson != "" {
+		var p params
+		if err := json.Unmarshal([]byte(pjson), &p); err != nil {
+			return fmt.Errorf("invalid -param-json argument: %s", err)
+		}
+		for k, v := range p {
+			if _, ok := c.params[k]; !ok {
+				c.params[k] = v
+			}
+		}
+	}
+
+	// run-wide deadline, every host gets its own deadline derived from it in dispatch
+	c.ctx = context.Background()
+	if c.totaltmo > 0 {
+		var cancel context.CancelFunc
+		c.ctx, cancel = context.WithTimeout(c.ctx, c.totaltmo)
+		defer cancel()
+	}
+
+	// responses are recorded to fixture or served from fixture without connecting to the host
+	if recordfile != "" {
+		c.record = &fixture{}
+		defer func() {
+			if err := c.record.write(recordfile); err != nil {
+				c.out.Error("warning: cannot write record fixture: %s\n", err)
+			}
+		}()
+	}
+	if replayfile != "" {
+		if c.replay, err = loadFixture(replayfile); err != nil {
+			return err
+		}
+	}
+
+	if c.cacert != "" {
+		if c.roots, err = loadCACerts(c.cacert, c.caonly); err != nil {
+			return err
+		}
+	}
+	c.client = newClient(c)
+	if rate > 0 {
+		c.limiter = newRateLimiter(rate)
+	}
+	if c.trace {
+		c.hook = traceRequest(c)
+	}
+	if harfile != "" {
+		c.har = &harLog{}
+		defer func() {
+			if err := c.har.write(harfile); err != nil {
+				c.out.Error("warning: cannot write HAR file: %s\n", err)
+			}
+		}()
+	}
+
+	// call requested function for every host or for single host
+	switch {
+	case c.hostfile != "":
+		hosts, err := readHosts(c.hostfile)
+		if err != nil {
+			return err
+		}
+		return batch(c, hosts)
+	case c.group != "":
+		return batch(c, grouphosts)
+	case c.tgtstdin:
+		targets, err := readTargets(c.stdin)
+		if err != nil {
+			return err
+		}
+		return batch(c, targets)
 	}
+	return dispatch(c)
+}
 
-	// call requested function
+// dispatch runs function requested with flags for configured host within -host-timeout and -total-timeout
+func dispatch(c config) (err error) {
+	// errors carrying MessageId mapped with -map-message result in mapped exit code
+	defer func() {
+		if code, ok := c.msgcodes.exitCode(err); ok {
+			err = &exitError{code, err}
+		}
+	}()
+	// TLS parameters are reported once for every host
+	if c.tlsinfo || c.trace {
+		c.tlsonce = &sync.Once{}
+	}
+	// repeated reads of the same resource are served from memo until state changing request
+	if c.memo == nil {
+		c.memo = newResourceMemo()
+	}
+	// session of -token is shared, so it can be replaced when the token expires
+	if c.token != "" {
+		c.sess = &session{token: c.token}
+		defer closeSession(c)
+	}
+	total := c.ctx
+	if total == nil {
+		return dispatchFunc(c)
+	}
+	if total.Err() != nil {
+		return fmt.Errorf("total timeout of %s exceeded - host not processed", c.totaltmo)
+	}
+	if c.hosttmo > 0 {
+		var cancel context.CancelFunc
+		c.ctx, cancel = context.WithTimeout(total, c.hosttmo)
+		defer cancel()
+	}
+	err = dispatchFunc(c)
+	var rerr *redfishError
+	switch {
+	case err == nil:
+		return nil
+	case isLockout(err):
+		return lockoutHint(err)
+	case c.token != "" && errors.As(err, &rerr) && rerr.statusCode == http.StatusUnauthorized:
+		return fmt.Errorf("%w - session token is expired or invalid", err)
+	case total.Err() == context.DeadlineExceeded:
+		return fmt.Errorf("total timeout of %s exceeded - %s", c.totaltmo, err)
+	case c.ctx.Err() == context.DeadlineExceeded:
+		return fmt.Errorf("host timeout of %s exceeded - %s", c.hosttmo, err)
+	}
+	return err
+}
+
+// dispatchFunc calls function requested with flags
+func dispatchFunc(c config) error {
 	switch {
+	case c.shell:
+		return shell(c)
+	case c.get && c.list:
+		return getList(c)
 	case c.get:
 		return get(c)
+	case c.info:
+		return printInfo(c)
+	case c.bootprog:
+		return printBootProgress(c)
+	case c.bmcnet:
+		return printBMCNetwork(c)
+	case c.can != "":
+		return canPerform(c)
 	case c.list:
 		return list(c)
-	case c.action != "":
+	case c.target == "manager":
+		return resetToDefaults(c)
+	case c.secboot != "":
+		return setSecureBoot(c)
+	case len(c.actions) > 0:
 		return action(c)
+	case c.ensure != "":
+		return ensure(c)
+	case c.check:
+		return check(c)
+	case c.sel:
+		return sel(c)
+	case c.listall:
+		return listAll(c)
+	case c.listsys:
+		return listSystems(c)
+	case c.vercreds:
+		return verifyCredentials(c)
+	case c.getboot:
+		return getBootOrder(c)
+	case c.listboot:
+		return listBootOptions(c)
+	case c.compare != "":
+		return compare(c)
+	case c.rawout != "":
+		return rawToFile(c, c.rawpath)
+	case c.rawpath != "":
+		return raw(c, c.rawpath)
+	case c.setboot != "":
+		return setBootOrder(c)
+	case c.getsecb:
+		return printSecureBoot(c)
+	case c.export != "":
+		return exportProfile(c)
+	case c.conform:
+		return conformance(c)
+	case c.events:
+		return subscribe(c)
 	}
 	return fmt.Errorf("possible bug. don't know what to do")
 }
 
-// list prints out a list of supported power actions for specified hosts
+// newClient returns http client with transport configured according to specified config
+func newClient(c config) *http.Client {
+	transport := &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: c.insecure, ServerName: c.servname, RootCAs: c.roots},
+		ForceAttemptHTTP2: true,
+	}
+	// non-nil empty map disables HTTP/2 upgrade
+	if c.http1 {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	// interoperability with old BMC firmware, weakens security
+	if c.legacy {
+		transport.TLSClientConfig.Renegotiation = tls.RenegotiateOnceAsClient
+		transport.TLSClientConfig.MinVersion = tls.VersionTLS10
+		for _, suites := range [][]*tls.CipherSuite{tls.CipherSuites(), tls.InsecureCipherSuites()} {
+			for _, suite := range suites {
+				transport.TLSClientConfig.CipherSuites = append(transport.TLSClientConfig.CipherSuites, suite.ID)
+			}
+		}
+	}
+	// standard verification is replaced with one ignoring certificate validity dates
+	if c.igntime {
+		transport.TLSClientConfig.InsecureSkipVerify = true
+		transport.TLSClientConfig.VerifyPeerCertificate = verifyIgnoringTime(c, transport.TLSClientConfig)
+	}
+	// bind outgoing connections to source address on multi-homed hosts
+	var dialer net.Dialer
+	if c.srcip != "" {
+		dialer.LocalAddr = &net.TCPAddr{IP: net.ParseIP(c.srcip)}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, fmt.Errorf("cannot connect from source address %s: %w", c.srcip, err)
+			}
+			return conn, nil
+		}
+	}
+	// connect to dial address while keeping -host for urls, Host header and certificate verification
+	if c.dialaddr != "" {
+		network, addr := "tcp", c.dialaddr
+		if strings.HasPrefix(addr, "unix:") {
+			network, addr = "unix", strings.TrimPrefix(addr, "unix:")
+		}
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+	client := &http.Client{
+		Timeout:       time.Second * time.Duration(c.timeout),
+		Transport:     transport,
+		CheckRedirect: checkRedirect(c),
+	}
+	switch {
+	case c.replay != nil:
+		client.Transport = &replayTransport{c.replay}
+	case c.record != nil:
+		client.Transport = &recordingTransport{c, transport, c.record}
+	}
+	return client
+}
+
+// checkRedirect returns redirect policy re-applying credentials on same-host redirects
+// and refusing cross-host redirects if -no-follow-cross-host is set
+func checkRedirect(c config) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		if req.URL.Host != via[0].URL.Host {
+			if c.nocross {
+				return fmt.Errorf("refusing to follow cross-host redirect to %s", req.URL.Host)
+			}
+			return nil
+		}
+		for _, h := range []string{"Authorization", "X-Auth-Token"} {
+			if v := via[0].Header.Get(h); v != "" {
+				req.Header.Set(h, v)
+			}
+		}
+		return nil
+	}
+}
+
+// isTerminal returns true if reader or writer is a terminal (character device other than null device)
+func isTerminal(rw interface{}) bool {
+	f, ok := rw.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(fi, null) {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// count returns number of true values
+func count(values ...bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+// defaultUserAgent returns default User-Agent header value built from version info
+func defaultUserAgent() string {
+	if commit == "" {
+		return fmt.Sprintf("redpower/%s", version)
+	}
+	return fmt.Sprintf("redpower/%s (%s)", version, commit)
+}
+
+// printJSONError prints error in json format including redfish message id if available
+func printJSONError(c config, err error) {
+	var je struct {
+		Host  string `json:"host"`
+		OK    bool   `json:"ok"`
+		Error struct {
+			Message string `json:"message"`
+			Code    string `json:"code,omitempty"`
+			Status  int    `json:"status,omitempty"`
+		} `json:"error"`
+	}
+	je.Host = c.host
+	je.Error.Message = err.Error()
+	var rerr *redfishError
+	if errors.As(err, &rerr) {
+		je.Error.Code = rerr.messageID
+		je.Error.Status = rerr.statusCode
+	}
+	json.NewEncoder(resultWriter{c.out}).Encode(je)
+}
+
+// list prints out a list of supported power actions for specified hosts
+// currently only hosts with single computer system in redfish systems collection are supported
+func list(c config) error {
+	sys, err := getSystem(c)
+	if err != nil {
+		return err
+	}
+	vals, err := listedActions(c, sys)
+	if err != nil {
+		return err
+	}
+	if structured(c) {
+		res := result{Host: c.host}
+		for _, val := range vals {
+			res.AllowedActions = append(res.AllowedActions, describeAction(val))
+		}
+		return printResult(c, res)
+	}
+	if !c.quiet {
+		c.out.Info("host: %s allowed power actions:\n", c.host)
+	}
+	for _, val := range vals {
+		c.out.Result("%s\n", val)
+	}
+	return nil
+}
+
+// getList prints current power state and supported power actions of the system from single system resource
+func getList(c config) error {
+	sys, err := getSystem(c)
+	if err != nil {
+		return err
+	}
+	vals, err := listedActions(c, sys)
+	if err != nil {
+		return err
+	}
+	if structured(c) {
+		res := result{Host: c.host, PowerState: sys.PowerState}
+		for _, val := range vals {
+			res.AllowedActions = append(res.AllowedActions, describeAction(val))
+		}
+		return printResult(c, res)
+	}
+	if !c.quiet {
+		c.out.Info("host: %s power state: ", c.host)
+	}
+	c.out.Result("%s\n", sys.PowerState)
+	if !c.quiet {
+		c.out.Info("allowed power actions:\n")
+	}
+	for _, val := range vals {
+		c.out.Result("%s\n", val)
+	}
+	return nil
+}
+
+// canPerform prints whether the system supports action specified with -can and returns quiet error with exit code 3 if it does not
+// OEM actions are matched in namespace:action format like in -list output
+func canPerform(c config) error {
+	sys, err := getSystem(c)
+	if err != nil {
+		return err
+	}
+	vals, err := listedActions(c, sys)
+	if err != nil {
+		return err
+	}
+	for _, val := range vals {
+		if strings.EqualFold(val, c.can) {
+			if !c.quiet {
+				c.out.Info("host: %s %s: ", c.host, val)
+				c.out.Result("supported\n")
+			}
+			return nil
+		}
+	}
+	if !c.quiet {
+		c.out.Info("host: %s %s: ", c.host, c.can)
+		c.out.Result("not supported\n")
+	}
+	// negative answer is not a failure, so it is told apart from failed check by exit code only
+	return &exitError{exitUnsupported, &quietError{fmt.Errorf("%s is not supported by host %s", c.can, c.host)}}
+}
+
+// listedActions returns allowed reset types of the system followed by OEM actions labeled with vendor namespace
+func listedActions(c config, sys system) ([]string, error) {
+	vals, err := allowedActions(c, sys)
+	if err != nil {
+		return nil, err
+	}
+	for _, oa := range oemActions(sys, c.vendor) {
+		for _, v := range oa.values {
+			vals = append(vals, fmt.Sprintf("%s:%s", oa.namespace, v))
+		}
+	}
+	return vals, nil
+}
+
+// getAllowedActions returns a list of power actions allowed for specified host
+func getAllowedActions(c config) ([]string, error) {
+	sys, err := getSystem(c)
+	if err != nil {
+		return nil, err
+	}
+	return allowedActions(c, sys)
+}
+
+// allowedActions returns a list of power actions allowed for the system
+func allowedActions(c config, sys system) ([]string, error) {
+	if c.assumed != nil {
+		return c.assumed, nil
+	}
+	return allowableValues(c, sys.Actions.ComputerSystemReset)
+}
+
+// allowableValues returns a list of reset types allowed for the reset action
+func allowableValues(c config, ra resetAction) ([]string, error) {
+	vals := ra.ResetTypeRedfishAllowableValues
+	// workaround for old redfish versions
+	if ra.RedfishActionInfo != "" {
+		url := hostURL(c, ra.RedfishActionInfo)
+		b, err := redfishGet(c, url)
+		if err != nil {
+			return nil, err
+		}
+		var ainfo struct {
+			Parameters []struct {
+				AllowableValues []string `json:"AllowableValues"`
+			} `json:"Parameters"`
+		}
+		if err := json.Unmarshal(b, &ainfo); err != nil {
+			return nil, err
+		}
+		if len(ainfo.Parameters) > 0 {
+			vals = ainfo.Parameters[0].AllowableValues
+		}
+	}
+	return vals, nil
+}
+
+// get prints current power state for specified host, metrics are pushed to -pushgateway if set
+// currently only hosts with single computer system in redfish systems collection are supported
+func get(c config) error {
+	switch {
+	case c.output == "prometheus":
+		return getMetrics(c)
+	case c.target == "chassis":
+		return getChassis(c)
+	}
+	start := time.Now()
+	state, err := getPowerState(c)
+	if c.pushgw != "" {
+		pushMetrics(c, metrics([]hostMetrics{{c.host, state, err, time.Since(start)}}))
+	}
+	if err != nil {
+		return err
+	}
+	if structured(c) {
+		return printResult(c, result{Host: c.host, PowerState: state})
+	}
+	if !c.quiet {
+		c.out.Info("host: %s power state: ", c.host)
+	}
+	c.out.Result("%s\n", state)
+	return nil
+}
+
+// getPowerState returns current power state for specified host
+func getPowerState(c config) (string, error) {
+	sys, err := getSystem(c)
+	if err != nil {
+		return "", err
+	}
+	return sys.PowerState, nil
+}
+
+// action performs selected actions in sequence on specified host, stopping on first failure unless -keep-going is set
+// outcome of every step is summarized after sequence of multiple actions
 // currently only hosts with single computer system in redfish systems collection are supported
-func list(c config) error {
+func action(c config) (err error) {
+	if err := checkPolicy(c); err != nil {
+		return err
+	}
+	// results performed so far are printed in structured output and posted to webhook even if some action fails
+	res := result{Host: c.host}
+	if c.webhook != "" {
+		defer func() { notifyWebhook(c, res, err) }()
+	}
 	sys, err := getSystem(c)
 	if err != nil {
 		return err
 	}
-	vals := sys.Actions.ComputerSystemReset.ResetTypeRedfishAllowableValues
-	// workaround for old redfish versions
-	if sys.Actions.ComputerSystemReset.RedfishActionInfo != "" {
-		url := fmt.Sprintf("https://%s%s", c.host, sys.Actions.ComputerSystemReset.RedfishActionInfo)
-		b, err := redfishGet(c, url)
-		if err != nil {
-			return err
+	allowed, err := allowedActions(c, sys)
+	if err != nil {
+		return err
+	}
+	if structured(c) {
+		defer func() {
+			if perr := printResult(c, res); err == nil {
+				err = perr
+			}
+		}()
+	}
+	if c.delay > 0 {
+		if !c.quiet {
+			c.out.Info("waiting %s before performing action on host %s ...\n", c.delay, c.host)
 		}
-		var ainfo struct {
-			Parameters []struct {
-				AllowableValues []string `json:"AllowableValues"`
-			} `json:"Parameters"`
+		if err := sleep(c, c.delay); err != nil {
+			return fmt.Errorf("delay interrupted - no action performed: %s", err)
 		}
-		if err := json.Unmarshal(b, &ainfo); err != nil {
-			return err
+	}
+	// change summary covers actions performed so far, also when some of them failed
+	if c.diff {
+		defer func() {
+			if len(res.Actions) == 0 {
+				return
+			}
+			d, derr := diffState(c, sys)
+			if derr != nil {
+				c.out.Error("warning: cannot read power state after actions: %s\n", derr)
+				return
+			}
+			res.Diff = d
+		}()
+	}
+	// outcome of every step of the sequence is summarized at the end, steps after failure are not performed
+	steps := make([]string, len(c.actions))
+	for i, act := range c.actions {
+		steps[i] = act + " not performed"
+	}
+	if len(c.actions) > 1 && !c.quiet && !structured(c) {
+		defer func() { c.out.Info("host: %s sequence: %s\n", c.host, strings.Join(steps, ", ")) }()
+	}
+	failed, skipped := 0, 0
+	for i, act := range c.actions {
+		act = canonicalAction(act, allowed)
+		steps[i] = act + " failed"
+		if !c.quiet {
+			c.out.Info("performing %s action on host %s ...\n", act, c.host)
 		}
-		if len(ainfo.Parameters) > 0 {
-			vals = ainfo.Parameters[0].AllowableValues
+		var r actionResult
+		var err error
+		if strings.EqualFold(act, acCycleAction) {
+			r, err = acCycle(c, sys, allowed)
+			act = "PowerCycle"
+		} else {
+			r, err = performAction(c, sys, act)
+		}
+		if err == nil {
+			printActionResult(c, r)
+			switch {
+			case c.wait:
+				// task created by the action explains failure better than power state which was not reached
+				if r.TaskURL != "" && isTask(r.TaskURL, "") {
+					err = followTask(c, r.TaskURL)
+				}
+				if err == nil {
+					err = waitInterruptible(c, sys, act)
+				}
+				// reaching expected power state confirms action with empty response
+				if err == nil && r.Outcome == outcomeAmbiguous {
+					r.Outcome = outcomeSuccess
+				}
+			case c.verify:
+				verifyAction(c, sys, act)
+			}
+		}
+		if c.skipuns && errors.Is(err, errUnsupported) {
+			c.out.Error("warning: %s - %s action skipped\n", err, act)
+			steps[i] = act + " skipped"
+			skipped++
+			continue
+		}
+		if err != nil {
+			var rerr *redfishError
+			if errors.As(err, &rerr) {
+				r.Status = rerr.statusCode
+			}
+			if c.dumpallw && (r.Status == http.StatusBadRequest || r.Status == http.StatusMethodNotAllowed) {
+				dumpAllowable(c)
+			}
+			r.Action, r.Outcome, r.Error = act, outcomeFailed, err.Error()
+		}
+		res.Actions = append(res.Actions, r)
+		if err == nil {
+			steps[i] = act + " ok"
+		}
+		if err != nil {
+			if !c.keepon || len(c.actions) == 1 {
+				return err
+			}
+			c.out.Error("error: %s\n", err)
+			failed++
 		}
 	}
-	if !c.quiet {
-		fmt.Fprintf(c.stdout, "host: %s allowed power actions:\n", c.host)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d actions failed", failed, len(c.actions))
 	}
-	for _, val := range vals {
-		fmt.Fprintln(c.stdout, val)
+	if c.waittcp != "" {
+		if err := waitForTCP(c); err != nil {
+			return err
+		}
+	}
+	if skipped > 0 {
+		return &exitError{exitUnsupported, fmt.Errorf("%d of %d actions not supported on host %s", skipped, len(c.actions), c.host)}
 	}
 	return nil
 }
 
-// get returns current power state for specified host
-// currently only hosts with single computer system in redfish systems collection are supported
-func get(c config) error {
-	sys, err := getSystem(c)
+// performAction performs single action on already discovered system and returns its result
+// with -skip-unsupported missing reset action target and 404 or 405 response are reported as errUnsupported
+func performAction(c config, sys system, act string) (actionResult, error) {
+	if c.skipuns && sys.Actions.ComputerSystemReset.Target == "" {
+		return actionResult{}, errUnsupported
+	}
+	url := hostURL(c, sys.Actions.ComputerSystemReset.Target)
+	tracef(c, "action target: %s reset type: %s", url, act)
+	payload := map[string]interface{}{}
+	for k, v := range c.params {
+		payload[k] = v
+	}
+	payload["ResetType"] = act
+	resp, body, err := redfishPost(c, url, payload)
+	var rerr *redfishError
+	if c.skipuns && errors.As(err, &rerr) && (rerr.statusCode == http.StatusNotFound || rerr.statusCode == http.StatusMethodNotAllowed) {
+		return actionResult{}, fmt.Errorf("%w (%s)", errUnsupported, rerr)
+	}
 	if err != nil {
-		return err
+		return actionResult{}, err
 	}
-	if !c.quiet {
-		fmt.Fprintf(c.stdout, "host: %s power state: ", c.host)
+	return newActionResult(act, resp, body), nil
+}
+
+// newActionResult returns result of action accepted by the host with its task reference and outcome
+func newActionResult(act string, resp *http.Response, body []byte) actionResult {
+	r := actionResult{Action: act, Status: resp.StatusCode, Async: resp.StatusCode == http.StatusAccepted}
+	r.TaskURL, r.TaskID = taskReference(resp, body)
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		r.Outcome = outcomeIgnoredConflict
+	case r.Async:
+		r.Outcome = outcomeAccepted
+	case r.TaskURL == "" && r.TaskID == "" && len(bytes.TrimSpace(body)) == 0:
+		r.Outcome = outcomeAmbiguous
+	default:
+		r.Outcome = outcomeSuccess
 	}
-	fmt.Fprintln(c.stdout, sys.PowerState)
-	return nil
+	return r
 }
 
-// action performs selected action on specified host
-// currently only hosts with single computer system in redfish systems collection are supported
-func action(c config) error {
+// printActionResult prints result of performed action unless -quiet or -no-ok is set
+func printActionResult(c config, r actionResult) {
+	if c.quiet || c.nook {
+		return
+	}
+	switch {
+	case r.Outcome == outcomeIgnoredConflict:
+		c.out.Info("OK (ignored conflict)\n")
+	case r.Async && r.TaskID != "":
+		c.out.Info("OK (accepted, task: %s id: %s)\n", r.TaskURL, r.TaskID)
+	case r.Async && r.TaskURL != "":
+		c.out.Info("OK (accepted, task: %s)\n", r.TaskURL)
+	case r.TaskID != "":
+		c.out.Info("OK (task id: %s)\n", r.TaskID)
+	case r.Async:
+		c.out.Info("OK (accepted)\n")
+	case r.Outcome == outcomeAmbiguous && !c.wait && !c.verify:
+		c.out.Info("OK (empty response - not confirmed, use -wait or -verify to confirm)\n")
+	default:
+		c.out.Info("OK\n")
+	}
+}
+
+// dumpAllowable prints allowable reset types and power state of the system read again after rejected action
+// system is read again, so the output reflects state of the system at the time of failure
+func dumpAllowable(c config) {
 	sys, err := getSystem(c)
 	if err != nil {
-		return err
-	}
-	if !c.quiet {
-		fmt.Fprintf(c.stdout, "performing %s action on host %s ...\n", c.action, c.host)
+		c.out.Error("warning: cannot read system to print allowable reset types: %s\n", err)
+		return
 	}
-	url := fmt.Sprintf("https://%s%s", c.host, sys.Actions.ComputerSystemReset.Target)
-	data := fmt.Sprintf("{\"ResetType\":\"%s\"}", c.action)
-	_, err = redfishPost(c, url, data)
+	allowed, err := allowedActions(c, sys)
 	if err != nil {
+		c.out.Error("warning: cannot read allowable reset types: %s\n", err)
+		return
+	}
+	c.out.Error("host: %s power state: %s allowable reset types: %s\n", c.host, sys.PowerState, strings.Join(allowed, ", "))
+}
+
+// canonicalAction returns action spelled exactly as in the list of allowed actions reported by the host
+// or as in the list of standard redfish reset types, matching case-insensitively
+// unknown action is returned unchanged
+func canonicalAction(action string, allowed []string) string {
+	for _, vals := range [][]string{allowed, resetTypes} {
+		for _, val := range vals {
+			if strings.EqualFold(action, val) {
+				return val
+			}
+		}
+	}
+	return action
+}
+
+// check verifies that specified host accepts credentials and exactly one system can be selected
+func check(c config) error {
+	if _, err := getSystemURL(c); err != nil {
+		if !c.quiet {
+			c.out.Info("host: %s check: FAIL\n", c.host)
+		}
 		return err
 	}
+	if !c.quiet {
+		c.out.Info("host: %s check: ", c.host)
+	}
+	c.out.Result("OK\n")
 	return nil
 }
 
 // getSystem returns (partial) redfish system object for specified host or error
 // currently only hosts with single computer system in redfish systems collection are supported
 func getSystem(c config) (system, error) {
-	url, err := getSystemURL(c)
+	url, cached, err := resolveSystemURL(c)
 	if err != nil {
 		return system{}, err
 	}
 	b, err := redfishGet(c, url)
+	// cached system URL may be stale, resolve it again
+	var rerr *redfishError
+	if cached && errors.As(err, &rerr) && rerr.statusCode == http.StatusNotFound {
+		c.refresh = true
+		if url, _, err = resolveSystemURL(c); err != nil {
+			return system{}, err
+		}
+		b, err = redfishGet(c, url)
+	}
 	if err != nil {
 		return system{}, err
 	}
-	var sys system
-	if err := json.Unmarshal(b, &sys); err != nil {
+	sys, err := decodeSystem(c, b)
+	if err != nil {
 		return system{}, err
 	}
+	// aggregation layers may return proxy resource without power state linking to the real system
+	visited := map[string]bool{sys.OdataID: true}
+	for hop := 0; sys.PowerState == ""; hop++ {
+		link := relatedSystem(sys)
+		if link == "" || visited[link] {
+			break
+		}
+		if hop >= maxSystemHops {
+			return system{}, fmt.Errorf("system not found after following %d related links", maxSystemHops)
+		}
+		visited[link] = true
+		tracef(c, "system %s has no power state, following related link: %s", sys.OdataID, link)
+		b, err := redfishGet(c, hostURL(c, link))
+		if err != nil {
+			return system{}, err
+		}
+		if sys, err = decodeSystem(c, b); err != nil {
+			return system{}, err
+		}
+	}
+	if c.strict {
+		if err := checkRequired(sys); err != nil {
+			return system{}, err
+		}
+	}
+	return sys, nil
+}
+
+// decodeSystem decodes system resource, unknown fields are rejected with -strict-json
+func decodeSystem(c config, b []byte) (system, error) {
+	var sys system
+	if !c.strict {
+		err := json.Unmarshal(b, &sys)
+		return sys, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&sys); err != nil {
+		return system{}, fmt.Errorf("system resource does not match expected schema (-strict-json): %s", err)
+	}
 	return sys, nil
 }
 
+// checkRequired returns error if system resource lacks fields required for power control
+func checkRequired(sys system) error {
+	var missing []string
+	if sys.OdataID == "" {
+		missing = append(missing, "@odata.id")
+	}
+	if sys.PowerState == "" {
+		missing = append(missing, "PowerState")
+	}
+	if sys.Actions.ComputerSystemReset.Target == "" {
+		missing = append(missing, "Actions.#ComputerSystem.Reset.target")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("system resource does not match expected schema (-strict-json): missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// maximum number of related links followed from aggregated system resource
+const maxSystemHops = 3
+
+// relatedSystem returns path of the first system linked with RelatedItem or empty string if there is none
+func relatedSystem(sys system) string {
+	for _, items := range [][]odataLink{sys.Links.RelatedItem, sys.RelatedItem} {
+		for _, item := range items {
+			if strings.Contains(item.OdataID, "/Systems/") {
+				return item.OdataID
+			}
+		}
+	}
+	return ""
+}
+
 // getSystemURL returns URL for redfish computer system or error if 0 or more than 1 system is found in the systems collection
+// if -match-uuid or -match-serial is specified, system matching them is selected from the collection instead
 func getSystemURL(c config) (string, error) {
-	url := fmt.Sprintf("https://%s/redfish/v1/Systems", c.host)
+	if c.trace {
+		traceServiceRoot(c)
+	}
+	url := hostURL(c, "/redfish/v1/Systems")
+	if c.expand {
+		url += "?$expand=."
+	}
 	b, err := redfishGet(c, url)
+	var rerr *redfishError
+	if errors.As(err, &rerr) && rerr.statusCode == http.StatusNotFound {
+		return "", &exitError{exitNotRedfish, fmt.Errorf("not a Redfish Systems endpoint - %s: %s", url, err)}
+	}
 	if err != nil {
 		return "", err
 	}
-	systems, err := parseRedfishCollection(b)
+	systems, err := parseRedfishMembers(b)
 	if err != nil {
 		return "", err
 	}
+	tracef(c, "systems collection: %s members: %d", url, len(systems))
+	if c.muuid != "" || c.mserial != "" || c.filter != "" {
+		return matchSystemURL(c, systems)
+	}
 	switch l := len(systems); {
 	case l == 0:
-		return "", fmt.Errorf("no systems found in the redfish systems collection")
+		return "", &exitError{exitNoSystems, fmt.Errorf("host has no computer systems - redfish systems collection is empty")}
+	case l > 1 && c.single:
+		c.out.Error("warning: -assume-single is set - selecting first of %d systems: %s\n", l, systems[0].OdataID)
+	case l > 1:
+		return "", fmt.Errorf("multiple systems found in the redfish systems collection - not supported: %s", describeMembers(c, systems))
+	}
+	tracef(c, "selected system: %s", systems[0].OdataID)
+	return hostURL(c, systems[0].OdataID), nil
+}
+
+// matchSystemURL returns URL of the only system from the list matching -match-uuid, -match-serial and -filter
+// or error if none or more than 1 system matches
+// members of expanded collection are matched directly, other members are fetched first
+func matchSystemURL(c config, systems []member) (string, error) {
+	var matched []string
+	for _, m := range systems {
+		url := hostURL(c, m.OdataID)
+		b := []byte(m.raw)
+		if !m.expanded() {
+			var err error
+			if b, err = redfishGet(c, url); err != nil {
+				return "", err
+			}
+		}
+		var sys system
+		if err := json.Unmarshal(b, &sys); err != nil {
+			return "", err
+		}
+		if c.muuid != "" && !strings.EqualFold(sys.UUID, c.muuid) {
+			continue
+		}
+		if c.mserial != "" && !strings.EqualFold(sys.SerialNumber, c.mserial) && !strings.EqualFold(sys.SKU, c.mserial) {
+			continue
+		}
+		if c.filter != "" {
+			ok, err := matchFilter(b, c.filter)
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				continue
+			}
+		}
+		matched = append(matched, url)
+	}
+	switch l := len(matched); {
+	case l == 0:
+		return "", fmt.Errorf("no system matching -match-uuid, -match-serial or -filter found in the redfish systems collection")
 	case l > 1:
-		return "", fmt.Errorf("multiple systems found in the redfish systems collection - not supported")
+		return "", fmt.Errorf("multiple systems matching -match-uuid, -match-serial or -filter found in the redfish systems collection: %s", strings.Join(matched, ", "))
+	}
+	tracef(c, "selected matching system: %s", matched[0])
+	return matched[0], nil
+}
+
+// hostURL returns URL of specified path on configured host using configured scheme
+// path is normalized with cleanPath, as some BMCs reject paths with duplicate slashes
+func hostURL(c config, path string) string {
+	return fmt.Sprintf("%s://%s%s", c.scheme, strings.TrimRight(c.host, "/"), cleanPath(path))
+}
+
+// cleanPath returns path with leading slash and without duplicate slashes, trailing slash and query are kept
+func cleanPath(path string) string {
+	query := ""
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path, query = path[:i], path[i:]
+	}
+	for strings.Contains(path, "//") {
+		path = strings.Replace(path, "//", "/", -1)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path + query
+}
+
+// joinPath returns path of resource relative to parent resource with single slash between them
+func joinPath(parent, name string) string {
+	return strings.TrimRight(parent, "/") + "/" + strings.TrimLeft(name, "/")
+}
+
+// setHeaders sets common request headers and authenticates request using session token if available or basic auth otherwise
+func setHeaders(c config, req *http.Request) {
+	if c.sess != nil {
+		req.Header.Set("X-Auth-Token", c.sess.current())
+	} else if c.token != "" {
+		req.Header.Set("X-Auth-Token", c.token)
+	} else {
+		req.SetBasicAuth(c.user, c.pass)
 	}
-	return fmt.Sprintf("https://%s%s", c.host, systems[0]), nil
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.agent)
+	setDownstream(c, req)
+}
+
+// setDownstream sets header with credentials of downstream BMC if -downstream-user is set
+// primary authentication is used by the aggregator, so it is not affected
+func setDownstream(c config, req *http.Request) {
+	if c.dsuser != "" {
+		req.Header.Set(c.dsheader, "Basic "+downstreamAuth(c))
+	}
+}
+
+// downstreamAuth returns basic auth encoded downstream credentials
+func downstreamAuth(c config) string {
+	return base64.StdEncoding.EncodeToString([]byte(c.dsuser + ":" + c.dspass))
+}
+
+// doRequest sends http request using configured client, request rejected with 401 (Unauthorized) is sent once again
+// with new session if the session token expired and -user and -pass are available, unless -no-relogin is set
+func doRequest(c config, req *http.Request) (*http.Response, error) {
+	if c.ctx != nil {
+		req = req.WithContext(c.ctx)
+	}
+	resp, err := sendRequest(c, req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !canRelogin(c, req) {
+		return resp, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if err := c.sess.relogin(c, req.Header.Get("X-Auth-Token")); err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Auth-Token", c.sess.current())
+	if req.GetBody != nil {
+		if req.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return sendRequest(c, req)
+}
+
+// sendRequest sends http request, retrying it up to -retries times if it failed with transient error
+// every attempt is reported to the request hook if set
+func sendRequest(c config, req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if c.limiter != nil {
+			if err := c.limiter.wait(c); err != nil {
+				return nil, err
+			}
+		}
+		printRequest(c, req)
+		start := time.Now()
+		resp, err := c.client.Do(req)
+		if c.har != nil {
+			recordHAR(c, req, resp, start, err)
+		}
+		if c.tlsonce != nil && resp != nil && resp.TLS != nil {
+			c.tlsonce.Do(func() { printTLSInfo(c, resp.TLS) })
+		}
+		if c.hook != nil {
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			c.hook(req.Method, req.URL.String(), status, time.Since(start), err)
+		}
+		if attempt >= c.retries {
+			return resp, err
+		}
+		delay, retry := retryDelay(c, req, resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := sleep(c, delay); err != nil {
+			return nil, err
+		}
+		// request body was consumed by previous attempt
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// default limit of response body size
+const defaultMaxBody = 16 << 20
+
+// readBody reads response body up to -max-response-size bytes or returns error if the body is larger
+// it protects against misbehaving endpoints streaming unbounded responses
+func readBody(c config, resp *http.Response) ([]byte, error) {
+	max := c.maxbody
+	if max <= 0 {
+		max = defaultMaxBody
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > max {
+		return nil, fmt.Errorf("response body exceeds -max-response-size of %d bytes", max)
+	}
+	return b, nil
 }
 
 // redfishGet sends http GET request to specified url and returns received reponse body or error
+// resource already read while processing the host is returned without sending the request again
 func redfishGet(c config, url string) ([]byte, error) {
-	client := &http.Client{
-		Timeout:   time.Second * time.Duration(c.timeout),
-		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: c.insecure}},
+	if b, ok := c.memo.get(url); ok {
+		tracef(c, "GET %s -> reused response read earlier", url)
+		return b, nil
 	}
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.SetBasicAuth(c.user, c.pass)
-	req.Header.Set("Accept", "application/json")
-	resp, err := client.Do(req)
+	setHeaders(c, req)
+	resp, err := doRequest(c, req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := readBody(c, resp)
 	if err != nil {
 		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
-		if c.debug {
-			fmt.Fprintf(c.stderr, "response status code: %d (%s)\n", resp.StatusCode, http.StatusText(resp.StatusCode))
-			fmt.Fprintln(c.stderr, "Response body:")
-			fmt.Fprintf(c.stderr, string(body))
-		}
-		return nil, fmt.Errorf("wrong response status code - expected: 200 (OK), got: %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+		printResponse(c, resp.StatusCode, body)
+		return nil, newRedfishError("200 (OK)", resp.StatusCode, body)
+	}
+	if err := checkJSON(resp, body); err != nil {
+		printResponse(c, resp.StatusCode, body)
+		return nil, err
 	}
+	c.memo.put(url, body)
 	return body, nil
 }
 
-// redfishPost sends http POST request with json encoded data to specified url and returns received reponse body or error
-func redfishPost(c config, url string, data string) ([]byte, error) {
-	client := &http.Client{
-		Timeout:   time.Second * time.Duration(c.timeout),
-		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: c.insecure}},
+// checkJSON returns error if response body is not json, like html login page returned by BMC or captive portal
+// body starting with json object or array is accepted regardless of declared content type
+func checkJSON(resp *http.Response, body []byte) error {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return nil
+	}
+	ctype := resp.Header.Get("Content-Type")
+	if ctype == "" {
+		ctype = http.DetectContentType(body)
 	}
-	req, err := http.NewRequest("POST", url, strings.NewReader(data))
+	if i := strings.Index(ctype, ";"); i >= 0 {
+		ctype = ctype[:i]
+	}
+	return fmt.Errorf("expected JSON but got %s - authentication may have failed or this is not a Redfish endpoint", ctype)
+}
+
+// redfishPost sends http POST request with payload encoded as json to specified url and returns received response with its body or error
+// response body is already read and closed, conflict is not treated as error if -ignore is set
+func redfishPost(c config, url string, payload interface{}) (*http.Response, []byte, error) {
+	// resources read so far may be changed by the request
+	c.memo.invalidate()
+	data, err := json.Marshal(payload)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	req.SetBasicAuth(c.user, c.pass)
-	req.Header.Set("Accept", "application/json")
+	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	// some BMCs reject chunked requests, body length must always be sent in Content-Length header
+	req.ContentLength = int64(len(data))
+	setHeaders(c, req)
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
+	resp, err := doRequest(c, req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := readBody(c, resp)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if c.ignore && resp.StatusCode == http.StatusConflict {
-		if !c.quiet {
-			fmt.Fprintln(c.stdout, "OK (ignored conflict)")
+		return resp, body, nil
+	}
+	if (resp.StatusCode != http.StatusOK) && (resp.StatusCode != http.StatusAccepted) && (resp.StatusCode != http.StatusNoContent) {
+		printResponse(c, resp.StatusCode, body)
+		rerr := newRedfishError("200 (OK), 202 (Accepted) or 204 (NoContent)", resp.StatusCode, body)
+		switch resp.StatusCode {
+		case http.StatusMethodNotAllowed:
+			rerr.hint = "BMC rejected the reset action (405) - the action may be unsupported or require a license"
+		case http.StatusLengthRequired:
+			rerr.hint = "BMC rejected request without Content-Length (411) - a proxy between redpower and BMC may be re-encoding the request body"
 		}
-		return body, nil
+		return nil, nil, rerr
+	}
+	return resp, body, nil
+}
+
+// redfishPatch sends http PATCH request with payload encoded as json to specified url and returns received response with its body or error
+// etag is sent in If-Match header if not empty, response body is already read and closed
+func redfishPatch(c config, url string, payload interface{}, etag string) (*http.Response, []byte, error) {
+	// resources read so far may be changed by the request
+	c.memo.invalidate()
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest("PATCH", url, bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	setHeaders(c, req)
+	req.Header.Set("Content-Type", "application/json")
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+	resp, err := doRequest(c, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := readBody(c, resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	if (resp.StatusCode != http.StatusOK) && (resp.StatusCode != http.StatusAccepted) && (resp.StatusCode != http.StatusNoContent) {
+		printResponse(c, resp.StatusCode, body)
+		return nil, nil, newRedfishError("200 (OK), 202 (Accepted) or 204 (NoContent)", resp.StatusCode, body)
+	}
+	return resp, body, nil
+}
+
+// createSession creates redfish session for configured user and returns session token and session URL or error
+func createSession(c config) (string, string, error) {
+	url := hostURL(c, "/redfish/v1/SessionService/Sessions")
+	data, err := json.Marshal(map[string]string{"UserName": c.user, "Password": c.pass})
+	if err != nil {
+		return "", "", err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", c.agent)
+	setDownstream(c, req)
+	resp, err := doRequest(c, req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	body, err := readBody(c, resp)
+	if err != nil {
+		return "", "", err
+	}
+	if (resp.StatusCode != http.StatusCreated) && (resp.StatusCode != http.StatusOK) {
+		printResponse(c, resp.StatusCode, body)
+		return "", "", newRedfishError("201 (Created) or 200 (OK)", resp.StatusCode, body)
+	}
+	token := resp.Header.Get("X-Auth-Token")
+	if token == "" {
+		return "", "", fmt.Errorf("no session token received from %s", c.host)
+	}
+	location := resp.Header.Get("Location")
+	if strings.HasPrefix(location, "/") {
+		location = hostURL(c, location)
+	}
+	return token, location, nil
+}
+
+// deleteSession sends http DELETE request for specified session url to log out or returns error
+func deleteSession(c config, url string) error {
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	setHeaders(c, req)
+	resp, err := doRequest(c, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := readBody(c, resp)
+	if err != nil {
+		return err
 	}
 	if (resp.StatusCode != http.StatusOK) && (resp.StatusCode != http.StatusNoContent) {
-		if c.debug {
-			fmt.Fprintf(c.stderr, "response status code: %d (%s)\n", resp.StatusCode, http.StatusText(resp.StatusCode))
-			fmt.Fprintln(c.stderr, "Response body:")
-			fmt.Fprintf(c.stderr, string(body))
-		}
-		return nil, fmt.Errorf("wrong response status code - expected: 200 (OK) or 204 (NoContent), got: %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+		printResponse(c, resp.StatusCode, body)
+		return newRedfishError("200 (OK) or 204 (NoContent)", resp.StatusCode, body)
 	}
-	if !c.quiet {
-		fmt.Fprintln(c.stdout, "OK")
+	return nil
+}
+
+// printResponse prints response status code and body if debug is enabled
+func printResponse(c config, statusCode int, body []byte) {
+	if c.debug {
+		c.out.Debug("response status code: %d (%s)\n", statusCode, http.StatusText(statusCode))
+		c.out.Debug("Response body:\n")
+		c.out.Debug("%s", redactBody(string(body)))
 	}
-	return body, nil
 }
 
 // parseRedfishCollection parses redfish collection and returns a list of members in a slice or error if collection cannot be parsed
+// Members@odata.count is ignored, as some firmware misreports it
 func parseRedfishCollection(b []byte) ([]string, error) {
 	var rc struct {
 		Members []struct {
 			OdataID string `json:"@odata.id"`
 		} `json:"Members"`
-		Count int `json:"Members@odata.count"`
 	}
 	if err := json.Unmarshal(b, &rc); err != nil {
 		return nil, err
 	}
-	result := make([]string, rc.Count)
+	result := make([]string, len(rc.Members))
 	for i, member := range rc.Members {
 		result[i] = member.OdataID
 	}
 	return result, nil
 }
+
+// type member describes member of redfish collection with its name and id if collection is expanded
+type member struct {
+	OdataID string `json:"@odata.id"`
+	ID      string `json:"Id"`
+	Name    string `json:"Name"`
+	raw     json.RawMessage
+}
+
+// expanded returns true if member contains more than a link to the resource
+func (m member) expanded() bool {
+	return m.ID != "" || m.Name != ""
+}
+
+// parseRedfishMembers parses redfish collection and returns its members or error if collection cannot be parsed
+func parseRedfishMembers(b []byte) ([]member, error) {
+	var rc struct {
+		Members []json.RawMessage `json:"Members"`
+	}
+	if err := json.Unmarshal(b, &rc); err != nil {
+		return nil, err
+	}
+	result := make([]member, len(rc.Members))
+	for i, raw := range rc.Members {
+		if err := json.Unmarshal(raw, &result[i]); err != nil {
+			return nil, err
+		}
+		result[i].raw = raw
+	}
+	return result, nil
+}
+
+// describeMembers returns comma separated list of members with their names, fetching members of not expanded collection
+func describeMembers(c config, members []member) string {
+	var names []string
+	for _, m := range members {
+		if !m.expanded() {
+			if b, err := redfishGet(c, hostURL(c, m.OdataID)); err == nil {
+				json.Unmarshal(b, &m)
+			}
+		}
+		names = append(names, m.label())
+	}
+	return strings.Join(names, ", ")
+}
+
+// label returns member URI followed by its name or id if known
+func (m member) label() string {
+	switch {
+	case m.Name != "":
+		return fmt.Sprintf("%s (%s)", m.OdataID, m.Name)
+	case m.ID != "":
+		return fmt.Sprintf("%s (%s)", m.OdataID, m.ID)
+	}
+	return m.OdataID
+}
+
+// newRedfishError returns redfish error for unexpected response status code with message decoded from response body if possible
+func newRedfishError(expected string, statusCode int, b []byte) *redfishError {
+	var re struct {
+		Error struct {
+			Code         string `json:"code"`
+			Message      string `json:"message"`
+			ExtendedInfo []struct {
+				MessageID string `json:"MessageId"`
+				Message   string `json:"Message"`
+			} `json:"@Message.ExtendedInfo"`
+		} `json:"error"`
+	}
+	e := &redfishError{expected: expected, statusCode: statusCode}
+	if err := json.Unmarshal(b, &re); err != nil {
+		return e
+	}
+	e.messageID, e.message = re.Error.Code, re.Error.Message
+	// extended info is more specific than general error code
+	if len(re.Error.ExtendedInfo) > 0 {
+		e.messageID, e.message = re.Error.ExtendedInfo[0].MessageID, re.Error.ExtendedInfo[0].Message
+	}
+	return e
+}
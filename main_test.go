@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestServer starts mock redfish service over http accepting specified credentials
+// wrap is applied to mock handler if not nil, so tests can alter responses of the mock
+func newTestServer(t *testing.T, user, pass string, wrap func(http.Handler) http.Handler) (*mockServer, *httptest.Server) {
+	t.Helper()
+	m := &mockServer{user: user, pass: pass, power: "Off"}
+	h := m.handler()
+	if wrap != nil {
+		h = wrap(h)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return m, srv
+}
+
+// runTest runs the program against test server with specified arguments and returns its standard output and error output
+func runTest(t *testing.T, srv *httptest.Server, args ...string) (string, string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	host := strings.TrimPrefix(srv.URL, "http://")
+	err := run(append([]string{"redpower", "-scheme", "http", "-host", host}, args...), strings.NewReader(""), &stdout, &stderr)
+	return stdout.String(), stderr.String(), err
+}
+
+// setenv sets environment variable for the duration of the test
+func setenv(t *testing.T, name, value string) {
+	t.Helper()
+	old, ok := os.LookupEnv(name)
+	os.Setenv(name, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	})
+}
+
+// configValue returns value and source of the flag printed with -print-config
+func configValue(t *testing.T, args ...string) (string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"redpower", "-host", "bmc.example.com", "-user", "u", "-pass", "p", "-print-config"}, args...)
+	if err := run(args, strings.NewReader(""), &stdout, &stderr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "insecure" {
+			return fields[1], strings.Join(fields[2:], " ")
+		}
+	}
+	t.Fatalf("insecure flag not printed:\n%s", stdout.String())
+	return "", ""
+}
+
+func TestInsecureEnvironmentDefault(t *testing.T) {
+	setenv(t, "REDPOWER_INSECURE", "1")
+	tests := []struct {
+		args   []string
+		value  string
+		source string
+	}{
+		{nil, "true", "environment REDPOWER_INSECURE"},
+		{[]string{"-insecure=false"}, "false", "command line"},
+		{[]string{"-servername", "bmc"}, "false", "default"},
+		{[]string{"-ignore-cert-time"}, "false", "default"},
+		{[]string{"-cacert", "ca.pem"}, "false", "default"},
+	}
+	for _, tt := range tests {
+		value, source := configValue(t, tt.args...)
+		if value != tt.value || source != tt.source {
+			t.Errorf("%v: insecure = %s (%s), want %s (%s)", tt.args, value, source, tt.value, tt.source)
+		}
+	}
+}
+
+func TestInsecureEnvironmentRequiresConfirmation(t *testing.T) {
+	setenv(t, "REDPOWER_INSECURE", "1")
+	var stdout, stderr bytes.Buffer
+	err := run([]string{"redpower", "-host", "127.0.0.1:1", "-user", "u", "-pass", "p", "-get"}, strings.NewReader(""), &stdout, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "-i-know-this-is-insecure") {
+		t.Errorf("non-interactive use with REDPOWER_INSECURE=1 returned %v, want confirmation error", err)
+	}
+}
+
+func TestInsecureEnvironmentGivesWayToServername(t *testing.T) {
+	setenv(t, "REDPOWER_INSECURE", "1")
+	_, srv := newTestServer(t, "u", "p", nil)
+	if _, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-servername", "bmc", "-get"); err != nil {
+		t.Errorf("-servername with REDPOWER_INSECURE=1 returned error: %s", err)
+	}
+}
+
+func TestQuietOnSuccessRejectsInteractiveModes(t *testing.T) {
+	for _, mode := range []string{"-shell", "-subscribe"} {
+		var stdout, stderr bytes.Buffer
+		err := run([]string{"redpower", "-host", "127.0.0.1:1", "-user", "u", "-pass", "p", "-quiet-on-success", mode}, strings.NewReader(""), &stdout, &stderr)
+		if err == nil || !strings.Contains(err.Error(), "-quiet-on-success cannot be used with "+mode) {
+			t.Errorf("-quiet-on-success with %s returned %v, want validation error", mode, err)
+		}
+	}
+}
+
+func TestCanPerform(t *testing.T) {
+	_, srv := newTestServer(t, "u", "p", nil)
+	tests := []struct {
+		action string
+		code   int
+	}{
+		{"ForceOff", 0},
+		{"forceoff", 0},
+		{"Hibernate", exitUnsupported},
+	}
+	for _, tt := range tests {
+		_, stderr, err := runTest(t, srv, "-user", "u", "-pass", "p", "-quiet", "-can", tt.action)
+		code := 0
+		var eerr *exitError
+		switch {
+		case errors.As(err, &eerr):
+			code = eerr.code
+		case err != nil:
+			code = 1
+		}
+		if code != tt.code {
+			t.Errorf("-can %s exit code = %d (%v), want %d", tt.action, code, err, tt.code)
+		}
+		var qerr *quietError
+		if err != nil && !errors.As(err, &qerr) {
+			t.Errorf("-can %s returned error which is printed: %s", tt.action, err)
+		}
+		if stderr != "" {
+			t.Errorf("-can %s printed with -quiet: %s", tt.action, stderr)
+		}
+	}
+	// failed check is told apart from unsupported action
+	_, _, err := runTest(t, srv, "-user", "u", "-pass", "wrong", "-quiet", "-can", "ForceOff")
+	var eerr *exitError
+	if err == nil || errors.As(err, &eerr) && eerr.code == exitUnsupported {
+		t.Errorf("-can with rejected credentials returned %v, want ordinary error", err)
+	}
+}
+
+func TestActionBodyWithQuotes(t *testing.T) {
+	var mu sync.Mutex
+	var body []byte
+	_, srv := newTestServer(t, "u", "p", func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				mu.Lock()
+				body, _ = ioutil.ReadAll(r.Body)
+				mu.Unlock()
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	for _, act := range []string{`On"}{"Extra":"x`, `Force\Off`, `"`} {
+		if _, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-quiet", "-action", act); err != nil {
+			t.Fatalf("-action %s returned error: %s", act, err)
+		}
+		mu.Lock()
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("-action %s posted malformed body %s: %s", act, body, err)
+		}
+		if len(payload) != 1 || payload["ResetType"] != act {
+			t.Errorf("-action %s posted body %s, want only ResetType %q", act, body, act)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestCanonicalAction(t *testing.T) {
+	for _, rt := range resetTypes {
+		for _, input := range []string{rt, strings.ToLower(rt), strings.ToUpper(rt)} {
+			if got := canonicalAction(input, nil); got != rt {
+				t.Errorf("canonicalAction(%s) = %s, want %s", input, got, rt)
+			}
+		}
+	}
+	// spelling of the BMC takes precedence, as it is what the BMC accepts
+	allowed := []string{"On", "forceOff", "GRACEFULSHUTDOWN"}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"ForceOff", "forceOff"},
+		{"gracefulshutdown", "GRACEFULSHUTDOWN"},
+		{"powercycle", "PowerCycle"},
+		{"Hibernate", "Hibernate"},
+	}
+	for _, tt := range tests {
+		if got := canonicalAction(tt.input, allowed); got != tt.want {
+			t.Errorf("canonicalAction(%s, %v) = %s, want %s", tt.input, allowed, got, tt.want)
+		}
+	}
+}
+
+func TestMixedCaseActionPostsCanonicalSpelling(t *testing.T) {
+	var mu sync.Mutex
+	var posted []string
+	_, srv := newTestServer(t, "u", "p", func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				var payload struct {
+					ResetType string `json:"ResetType"`
+				}
+				json.NewDecoder(r.Body).Decode(&payload)
+				mu.Lock()
+				posted = append(posted, payload.ResetType)
+				mu.Unlock()
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	if _, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-quiet", "-action", "forceoff,ON,gracefulRestart,pushpowerbutton"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []string{"ForceOff", "On", "GracefulRestart", "PushPowerButton"}
+	if strings.Join(posted, ",") != strings.Join(want, ",") {
+		t.Errorf("posted reset types %v, want %v", posted, want)
+	}
+}
+
+// redirectSystems returns handler redirecting systems collection to location, collection with trailing slash is served by the mock
+func redirectSystems(location string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/redfish/v1/Systems":
+				http.Redirect(w, r, location, http.StatusMovedPermanently)
+				return
+			case "/redfish/v1/Systems/":
+				r.URL.Path = "/redfish/v1/Systems"
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestRedirectToTrailingSlash(t *testing.T) {
+	_, srv := newTestServer(t, "u", "p", redirectSystems("/redfish/v1/Systems/"))
+	stdout, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-quiet", "-get")
+	if err != nil {
+		t.Fatalf("redirected systems collection returned error: %s", err)
+	}
+	if strings.TrimSpace(stdout) != "Off" {
+		t.Errorf("power state = %q, want Off", stdout)
+	}
+}
+
+func TestCrossHostRedirect(t *testing.T) {
+	_, other := newTestServer(t, "u", "p", nil)
+	_, srv := newTestServer(t, "u", "p", redirectSystems(other.URL+"/redfish/v1/Systems"))
+	_, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-quiet", "-no-follow-cross-host", "-get")
+	if err == nil || !strings.Contains(err.Error(), "refusing to follow cross-host redirect") {
+		t.Errorf("cross-host redirect with -no-follow-cross-host returned %v, want refusal", err)
+	}
+}
+
+func TestTransportHTTPVersion(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	tests := []struct {
+		http1 bool
+		proto string
+	}{
+		{false, "HTTP/2.0"},
+		{true, "HTTP/1.1"},
+	}
+	for _, tt := range tests {
+		client := newClient(config{insecure: true, http1: tt.http1})
+		transport := client.Transport.(*http.Transport)
+		if transport.ForceAttemptHTTP2 == tt.http1 {
+			t.Errorf("http1 %t: ForceAttemptHTTP2 = %t", tt.http1, transport.ForceAttemptHTTP2)
+		}
+		if pinned := transport.TLSNextProto != nil && len(transport.TLSNextProto) == 0; pinned != tt.http1 {
+			t.Errorf("http1 %t: TLSNextProto = %v, want empty non-nil map only with -http1", tt.http1, transport.TLSNextProto)
+		}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("http1 %t: unexpected error: %s", tt.http1, err)
+		}
+		resp.Body.Close()
+		if resp.Proto != tt.proto {
+			t.Errorf("http1 %t: negotiated %s, want %s", tt.http1, resp.Proto, tt.proto)
+		}
+	}
+}
+
+func TestActionBodyContentLength(t *testing.T) {
+	var mu sync.Mutex
+	var length int64
+	var chunked bool
+	var body []byte
+	_, srv := newTestServer(t, "u", "p", func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				mu.Lock()
+				length, chunked = r.ContentLength, len(r.TransferEncoding) > 0
+				body, _ = ioutil.ReadAll(r.Body)
+				mu.Unlock()
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	if _, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-quiet", "-action", "On"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if chunked || length != int64(len(body)) || length == 0 {
+		t.Errorf("action body of %d bytes sent with Content-Length %d (chunked %t)", len(body), length, chunked)
+	}
+}
+
+func TestLengthRequiredHint(t *testing.T) {
+	_, srv := newTestServer(t, "u", "p", func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				mockError(w, http.StatusLengthRequired, "Base.1.8.GeneralError", "length required")
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	_, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-quiet", "-action", "On")
+	if err == nil || !strings.Contains(err.Error(), "without Content-Length") {
+		t.Errorf("411 response returned %v, want error explaining missing Content-Length", err)
+	}
+}
+
+func TestOversizedResponseRejected(t *testing.T) {
+	// systems collection padded well over the limit
+	_, srv := newTestServer(t, "u", "p", func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/redfish/v1/Systems" {
+				mockJSON(w, http.StatusOK, map[string]interface{}{
+					"Members": []odataLink{{mockSystemURL}},
+					"Padding": strings.Repeat("x", 64*1024),
+				})
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	_, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-quiet", "-max-response-size", "4096", "-get")
+	if err == nil || !strings.Contains(err.Error(), "exceeds -max-response-size of 4096 bytes") {
+		t.Errorf("oversized response returned %v, want size limit error", err)
+	}
+	if _, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-quiet", "-get"); err != nil {
+		t.Errorf("response within default limit returned error: %s", err)
+	}
+}
+
+func TestHostURL(t *testing.T) {
+	tests := []struct {
+		host string
+		path string
+		want string
+	}{
+		{"bmc", "/redfish/v1/Systems", "https://bmc/redfish/v1/Systems"},
+		{"bmc", "redfish/v1/Systems", "https://bmc/redfish/v1/Systems"},
+		{"bmc/", "/redfish/v1/Systems", "https://bmc/redfish/v1/Systems"},
+		{"bmc:8443", "/redfish/v1//Systems/1", "https://bmc:8443/redfish/v1/Systems/1"},
+		{"bmc:8443/", "//redfish/v1/Systems/", "https://bmc:8443/redfish/v1/Systems/"},
+		{"[fe80::1]:443", "redfish/v1", "https://[fe80::1]:443/redfish/v1"},
+		{"bmc", "/redfish/v1/Systems?$expand=.($levels=1)", "https://bmc/redfish/v1/Systems?$expand=.($levels=1)"},
+	}
+	for _, tt := range tests {
+		if got := hostURL(config{scheme: "https", host: tt.host}, tt.path); got != tt.want {
+			t.Errorf("hostURL(%s, %s) = %s, want %s", tt.host, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	tests := []struct {
+		parent string
+		name   string
+		want   string
+	}{
+		{"/redfish/v1/Managers/1", "EthernetInterfaces", "/redfish/v1/Managers/1/EthernetInterfaces"},
+		{"/redfish/v1/Managers/1/", "EthernetInterfaces", "/redfish/v1/Managers/1/EthernetInterfaces"},
+		{"/redfish/v1/Managers/1", "/EthernetInterfaces", "/redfish/v1/Managers/1/EthernetInterfaces"},
+		{"/redfish/v1/Managers/1/", "/EthernetInterfaces", "/redfish/v1/Managers/1/EthernetInterfaces"},
+	}
+	for _, tt := range tests {
+		if got := joinPath(tt.parent, tt.name); got != tt.want {
+			t.Errorf("joinPath(%s, %s) = %s, want %s", tt.parent, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// standard reset types of manager ResetToDefaults action, used if the manager does not list allowed values
+var resetToDefaultsTypes = []string{"ResetAll", "PreserveNetworkAndUsers", "PreserveNetwork"}
+
+// resetToDefaults resets manager (BMC) to factory defaults using reset type from -reset-default-type
+// reset type is matched case-insensitively against values allowed by the manager
+func resetToDefaults(c config) error {
+	if err := checkPolicy(c); err != nil {
+		return err
+	}
+	url, err := getManagerURL(c)
+	if err != nil {
+		return err
+	}
+	b, err := redfishGet(c, url)
+	if err != nil {
+		return err
+	}
+	var mgr struct {
+		Actions map[string]json.RawMessage `json:"Actions"`
+	}
+	if err := json.Unmarshal(b, &mgr); err != nil {
+		return err
+	}
+	var ra resetAction
+	if raw, ok := mgr.Actions["#Manager.ResetToDefaults"]; ok {
+		if err := json.Unmarshal(raw, &ra); err != nil {
+			return err
+		}
+	}
+	if ra.Target == "" {
+		return fmt.Errorf("manager does not support ResetToDefaults action")
+	}
+	vals, err := allowableValues(c, ra)
+	if err != nil {
+		return err
+	}
+	if len(vals) == 0 {
+		vals = resetToDefaultsTypes
+	}
+	rt := ""
+	for _, val := range vals {
+		if strings.EqualFold(val, c.rdtype) {
+			rt = val
+		}
+	}
+	if rt == "" {
+		return fmt.Errorf("reset type %s is not allowed by the manager - allowed: %s", c.rdtype, strings.Join(vals, ", "))
+	}
+	target := hostURL(c, ra.Target)
+	tracef(c, "action target: %s reset type: %s", target, rt)
+	if !c.quiet {
+		c.out.Info("performing ResetToDefaults (%s) action on manager of host %s ...\n", rt, c.host)
+	}
+	resp, body, err := redfishPost(c, target, map[string]interface{}{"ResetType": rt})
+	if err != nil {
+		return err
+	}
+	printActionResult(c, newActionResult("ResetToDefaults", resp, body))
+	return nil
+}
+
+// type ethernetInterface describes (partial) redfish ethernet interface of the manager
+type ethernetInterface struct {
+	ID            string `json:"Id"`
+	MACAddress    string `json:"MACAddress"`
+	HostName      string `json:"HostName"`
+	IPv4Addresses []struct {
+		Address string `json:"Address"`
+	} `json:"IPv4Addresses"`
+}
+
+// printBMCNetwork prints MAC address, IPv4 addresses and host name of every ethernet interface of the manager
+func printBMCNetwork(c config) error {
+	url, err := getManagerURL(c)
+	if err != nil {
+		return err
+	}
+	b, err := redfishGet(c, url)
+	if err != nil {
+		return err
+	}
+	var mgr struct {
+		EthernetInterfaces odataLink `json:"EthernetInterfaces"`
+	}
+	if err := json.Unmarshal(b, &mgr); err != nil {
+		return err
+	}
+	ethURL := joinPath(url, "EthernetInterfaces")
+	if mgr.EthernetInterfaces.OdataID != "" {
+		ethURL = hostURL(c, mgr.EthernetInterfaces.OdataID)
+	}
+	if b, err = redfishGet(c, ethURL); err != nil {
+		return err
+	}
+	members, err := parseRedfishCollection(b)
+	if err != nil {
+		return err
+	}
+	if !c.quiet {
+		c.out.Info("host: %s manager network interfaces:\n", c.host)
+	}
+	w := tabwriter.NewWriter(resultWriter{c.out}, 0, 8, 2, ' ', 0)
+	if !c.quiet {
+		fmt.Fprintln(w, "INTERFACE\tMAC\tIPV4\tHOSTNAME")
+	}
+	for _, member := range members {
+		b, err := redfishGet(c, hostURL(c, member))
+		if err != nil {
+			return err
+		}
+		var eth ethernetInterface
+		if err := json.Unmarshal(b, &eth); err != nil {
+			return fmt.Errorf("cannot parse ethernet interface %s: %s", member, err)
+		}
+		var addrs []string
+		for _, a := range eth.IPv4Addresses {
+			if a.Address != "" {
+				addrs = append(addrs, a.Address)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", orDash(eth.ID), orDash(eth.MACAddress), orDash(strings.Join(addrs, ",")), orDash(eth.HostName))
+	}
+	return w.Flush()
+}
+
+// orDash returns s or - if s is empty, so empty table cells are visible
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// getManagerURL returns URL for redfish manager or error if 0 or more than 1 manager is found in the managers collection
+func getManagerURL(c config) (string, error) {
+	b, err := redfishGet(c, hostURL(c, "/redfish/v1/Managers"))
+	if err != nil {
+		return "", err
+	}
+	members, err := parseRedfishCollection(b)
+	if err != nil {
+		return "", err
+	}
+	switch l := len(members); {
+	case l == 0:
+		return "", fmt.Errorf("no managers found in the redfish managers collection")
+	case l > 1:
+		return "", fmt.Errorf("multiple managers found in the redfish managers collection - not supported")
+	}
+	return hostURL(c, members[0]), nil
+}
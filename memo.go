@@ -0,0 +1,46 @@
+package main
+
+import "sync"
+
+// type resourceMemo holds response bodies of resources read while processing single host, keyed by URL
+// it is invalidated by every state changing request, so reads after actions see fresh state
+type resourceMemo struct {
+	mu        sync.Mutex
+	resources map[string][]byte
+}
+
+// newResourceMemo returns empty memo
+func newResourceMemo() *resourceMemo {
+	return &resourceMemo{resources: map[string][]byte{}}
+}
+
+// get returns body of the resource read earlier, nil memo holds nothing
+func (m *resourceMemo) get(url string) ([]byte, bool) {
+	if m == nil {
+		return nil, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.resources[url]
+	return b, ok
+}
+
+// put records body of the resource
+func (m *resourceMemo) put(url string, b []byte) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.resources[url] = b
+	m.mu.Unlock()
+}
+
+// invalidate forgets all resources
+func (m *resourceMemo) invalidate() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.resources = map[string][]byte{}
+	m.mu.Unlock()
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// path of the only system provided by the mock server
+const mockSystemURL = "/redfish/v1/Systems/1"
+
+// power states resulting from reset types supported by the mock server
+// empty state means that the reset type toggles the power
+var mockTransitions = map[string]string{
+	"On":               "On",
+	"ForceOn":          "On",
+	"ForceOff":         "Off",
+	"GracefulShutdown": "Off",
+	"GracefulRestart":  "On",
+	"ForceRestart":     "On",
+	"PowerCycle":       "On",
+	"PushPowerButton":  "",
+	"Nmi":              "On",
+	"Pause":            "Paused",
+	"Resume":           "On",
+}
+
+// type mockServer describes minimal redfish service with single system and mutable power state
+type mockServer struct {
+	mu    sync.Mutex
+	user  string
+	pass  string
+	power string
+}
+
+// serveMock runs mock redfish service on address specified with -serve-mock until interrupted
+// https is served with self-signed certificate generated at start, so clients must use -insecure
+func serveMock(c config) error {
+	m := &mockServer{user: c.user, pass: c.pass, power: "Off"}
+	srv := &http.Server{Addr: c.mockaddr, Handler: m.handler()}
+
+	if c.scheme == "http" {
+		if !c.quiet {
+			c.out.Info("serving mock redfish service on http://%s\n", c.mockaddr)
+		}
+		return srv.ListenAndServe()
+	}
+	cert, err := mockCertificate()
+	if err != nil {
+		return fmt.Errorf("cannot generate mock server certificate: %s", err)
+	}
+	srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	if !c.quiet {
+		c.out.Info("serving mock redfish service on https://%s (self-signed certificate, use -insecure)\n", c.mockaddr)
+	}
+	return srv.ListenAndServeTLS("", "")
+}
+
+// handler returns handler serving all resources of the mock service
+func (m *mockServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redfish/v1", m.serviceRoot)
+	mux.HandleFunc("/redfish/v1/", m.serviceRoot)
+	mux.HandleFunc("/redfish/v1/Systems", m.authorized(m.systems))
+	mux.HandleFunc(mockSystemURL, m.authorized(m.system))
+	mux.HandleFunc(mockSystemURL+"/Actions/ComputerSystem.Reset", m.authorized(m.reset))
+	return mux
+}
+
+// mockCertificate returns self-signed certificate for localhost valid for one day
+func mockCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "redpower mock"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// authorized wraps handler with basic authentication using -user and -pass, any credentials are accepted if they are not set
+func (m *mockServer) authorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || (m.user != "" && user != m.user) || (m.pass != "" && pass != m.pass) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="redfish"`)
+			mockError(w, http.StatusUnauthorized, "Base.1.8.GeneralError", "invalid credentials")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// serviceRoot serves redfish service root, which does not require authentication
+func (m *mockServer) serviceRoot(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/redfish/v1" && r.URL.Path != "/redfish/v1/" {
+		mockError(w, http.StatusNotFound, "Base.1.8.ResourceMissingAtURI", fmt.Sprintf("resource %s not found", r.URL.Path))
+		return
+	}
+	mockJSON(w, http.StatusOK, map[string]interface{}{
+		"@odata.id":      "/redfish/v1",
+		"Id":             "RootService",
+		"Name":           "redpower mock",
+		"RedfishVersion": "1.6.0",
+		"Systems":        odataLink{"/redfish/v1/Systems"},
+	})
+}
+
+// systems serves systems collection with single member
+func (m *mockServer) systems(w http.ResponseWriter, r *http.Request) {
+	mockJSON(w, http.StatusOK, map[string]interface{}{
+		"@odata.id":           "/redfish/v1/Systems",
+		"Name":                "Computer System Collection",
+		"Members":             []odataLink{{mockSystemURL}},
+		"Members@odata.count": 1,
+	})
+}
+
+// system serves the system with current power state and reset action
+func (m *mockServer) system(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sys system
+	sys.OdataID = mockSystemURL
+	sys.PowerState = m.power
+	sys.UUID = "00000000-0000-0000-0000-000000000001"
+	sys.SerialNumber = "MOCK0001"
+	sys.Actions.ComputerSystemReset.Target = mockSystemURL + "/Actions/ComputerSystem.Reset"
+	for _, rt := range resetTypes {
+		if _, ok := mockTransitions[rt]; ok {
+			sys.Actions.ComputerSystemReset.ResetTypeRedfishAllowableValues = append(sys.Actions.ComputerSystemReset.ResetTypeRedfishAllowableValues, rt)
+		}
+	}
+	mockJSON(w, http.StatusOK, sys)
+}
+
+// reset performs reset action by changing power state of the system
+func (m *mockServer) reset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		mockError(w, http.StatusMethodNotAllowed, "Base.1.8.OperationNotAllowed", "reset action requires POST")
+		return
+	}
+	var payload struct {
+		ResetType string `json:"ResetType"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		mockError(w, http.StatusBadRequest, "Base.1.8.MalformedJSON", "request body cannot be parsed")
+		return
+	}
+	state, ok := mockTransitions[payload.ResetType]
+	if !ok {
+		mockError(w, http.StatusBadRequest, "Base.1.8.ActionParameterValueNotInList", fmt.Sprintf("reset type %s is not supported", payload.ResetType))
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case state == "" && m.power == "Off":
+		state = "On"
+	case state == "":
+		state = "Off"
+	case payload.ResetType == "Resume" && m.power != "Paused", payload.ResetType == "Pause" && m.power != "On":
+		mockError(w, http.StatusConflict, "Base.1.8.ResourceInStandby", fmt.Sprintf("reset type %s is not allowed in power state %s", payload.ResetType, m.power))
+		return
+	}
+	m.power = state
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mockJSON writes response with status code and json encoded value
+func mockJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("OData-Version", "4.0")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// mockError writes redfish error response
+func mockError(w http.ResponseWriter, status int, code, message string) {
+	mockJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+			"@Message.ExtendedInfo": []map[string]string{
+				{"MessageId": code, "Message": message},
+			},
+		},
+	})
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// type netrcEntry describes login and password of single machine or default entry of .netrc file
+type netrcEntry struct {
+	login    string
+	password string
+}
+
+// netrcPath returns path of .netrc file set with NETRC environment variable or located in user home directory
+func netrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// parseNetrc returns entries of .netrc file content indexed by machine name and default entry if present
+// macro definitions are skipped
+func parseNetrc(data string) (map[string]netrcEntry, *netrcEntry) {
+	machines := map[string]netrcEntry{}
+	var def *netrcEntry
+	var name string
+	var cur *netrcEntry
+	flush := func() {
+		if cur != nil && name != "" {
+			machines[name] = *cur
+		}
+	}
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		tokens := strings.Fields(lines[i])
+		for j := 0; j < len(tokens); j++ {
+			value := ""
+			if j+1 < len(tokens) {
+				value = tokens[j+1]
+			}
+			switch tokens[j] {
+			case "machine":
+				flush()
+				name, cur = value, &netrcEntry{}
+				j++
+			case "default":
+				flush()
+				name, cur = "", &netrcEntry{}
+				def = cur
+			case "login":
+				if cur != nil {
+					cur.login = value
+				}
+				j++
+			case "password":
+				if cur != nil {
+					cur.password = value
+				}
+				j++
+			case "account":
+				j++
+			case "macdef":
+				// macro body ends with empty line
+				for i++; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				}
+				j = len(tokens)
+			}
+		}
+	}
+	flush()
+	return machines, def
+}
+
+// netrcCredentials fills missing user and password using .netrc entry of the host or default entry
+// password is used only if user is not set or matches login of the entry
+func netrcCredentials(host, user, pass string) (string, string) {
+	path := netrcPath()
+	if path == "" {
+		return user, pass
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return user, pass
+	}
+	machines, def := parseNetrc(string(b))
+	e, ok := machines[host]
+	if !ok {
+		if name, _, err := net.SplitHostPort(host); err == nil {
+			e, ok = machines[name]
+		}
+	}
+	if !ok {
+		if def == nil {
+			return user, pass
+		}
+		e = *def
+	}
+	if user == "" {
+		user = e.login
+	}
+	if pass == "" && user == e.login {
+		pass = e.password
+	}
+	return user, pass
+}
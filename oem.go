@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// namespace prefixes of OEM actions for supported vendors
+var vendorNamespaces = map[string][]string{
+	"dell":   {"dell"},
+	"hpe":    {"hpe", "hp"},
+	"lenovo": {"lenovo"},
+}
+
+// type oemAction describes OEM reset or power action found in the system actions
+type oemAction struct {
+	namespace string
+	name      string
+	values    []string
+}
+
+// oemActions returns OEM reset and power actions of the system, limited to namespaces of specified vendor unless vendor is generic
+// actions can be defined directly in Actions.Oem or nested in vendor namespace objects
+func oemActions(sys system, vendor string) []oemAction {
+	var actions []oemAction
+	for key, raw := range sys.Actions.Oem {
+		if strings.HasPrefix(key, "#") {
+			actions = appendOEMAction(actions, strings.SplitN(strings.TrimPrefix(key, "#"), ".", 2)[0], key, raw)
+			continue
+		}
+		var nested map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &nested); err != nil {
+			continue
+		}
+		for name, raw := range nested {
+			if strings.HasPrefix(name, "#") {
+				actions = appendOEMAction(actions, key, name, raw)
+			}
+		}
+	}
+	var result []oemAction
+	for _, oa := range actions {
+		if vendor == "generic" || vendorMatches(vendor, oa.namespace) {
+			result = append(result, oa)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result
+}
+
+// appendOEMAction appends decoded action to the list if it is reset or power action with allowable values
+func appendOEMAction(actions []oemAction, namespace, name string, raw json.RawMessage) []oemAction {
+	lname := strings.ToLower(name)
+	if !strings.Contains(lname, "reset") && !strings.Contains(lname, "power") {
+		return actions
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return actions
+	}
+	oa := oemAction{namespace: namespace, name: name}
+	for k, v := range fields {
+		if strings.HasSuffix(k, "@Redfish.AllowableValues") {
+			var values []string
+			if err := json.Unmarshal(v, &values); err == nil {
+				oa.values = append(oa.values, values...)
+			}
+		}
+	}
+	if len(oa.values) == 0 {
+		return actions
+	}
+	return append(actions, oa)
+}
+
+// vendorMatches returns true if namespace belongs to specified vendor
+func vendorMatches(vendor, namespace string) bool {
+	for _, prefix := range vendorNamespaces[vendor] {
+		if strings.HasPrefix(strings.ToLower(namespace), prefix) {
+			return true
+		}
+	}
+	return false
+}
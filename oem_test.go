@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestOEMActions(t *testing.T) {
+	var sys system
+	err := json.Unmarshal([]byte(`{"Actions": {"Oem": {
+		"#DellOem.ChangePowerState": {
+			"target": "/redfish/v1/Systems/1/Actions/Oem/DellOem.ChangePowerState",
+			"PowerState@Redfish.AllowableValues": ["On", "Off"]
+		},
+		"#DellOem.Reboot": {
+			"target": "/redfish/v1/Systems/1/Actions/Oem/DellOem.Reboot"
+		},
+		"#DellOem.ExportConfig": {
+			"Format@Redfish.AllowableValues": ["XML"]
+		},
+		"Hpe": {
+			"#HpeComputerSystemExt.PowerButton": {
+				"target": "/redfish/v1/Systems/1/Actions/Oem/Hpe/HpeComputerSystemExt.PowerButton",
+				"PushType@Redfish.AllowableValues": ["Press", "PressAndHold"]
+			},
+			"Description": "not an action"
+		}
+	}}}`), &sys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dell := oemAction{namespace: "DellOem", name: "#DellOem.ChangePowerState", values: []string{"On", "Off"}}
+	hpe := oemAction{namespace: "Hpe", name: "#HpeComputerSystemExt.PowerButton", values: []string{"Press", "PressAndHold"}}
+	tests := []struct {
+		vendor string
+		want   []oemAction
+	}{
+		{"generic", []oemAction{dell, hpe}},
+		{"dell", []oemAction{dell}},
+		{"hpe", []oemAction{hpe}},
+		{"lenovo", nil},
+	}
+	for _, tt := range tests {
+		if got := oemActions(sys, tt.vendor); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("vendor %s: %+v, want %+v", tt.vendor, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// type result describes structured output shared by json, yaml and template formats
+type result struct {
+	Host           string         `json:"host"`
+	PowerState     string         `json:"powerState,omitempty"`
+	AllowedActions []actionInfo   `json:"allowedActions,omitempty"`
+	Actions        []actionResult `json:"actions,omitempty"`
+	Systems        []systemInfo   `json:"systems,omitempty"`
+	Info           *nodeInfo      `json:"info,omitempty"`
+	BootProgress   *bootProgress  `json:"bootProgress,omitempty"`
+	Diff           *stateDiff     `json:"diff,omitempty"`
+}
+
+// structured returns true if result should be printed in json, yaml, template or csv format
+func structured(c config) bool {
+	return c.output == "json" || c.output == "yaml" || c.output == "template" || c.output == "csv"
+}
+
+// csvHeader is header row of csv output
+var csvHeader = []string{"host", "state", "action", "result", "error"}
+
+// printResult prints result in the format selected with -output
+// template output is terminated with newline unless template ends with one
+func printResult(c config, r result) error {
+	switch c.output {
+	case "yaml":
+		return writeYAML(resultWriter{c.out}, r)
+	case "template":
+		var sb strings.Builder
+		if err := c.tmpl.Execute(&sb, r); err != nil {
+			return fmt.Errorf("cannot execute -template: %s", err)
+		}
+		if !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteString("\n")
+		}
+		c.out.Result("%s", sb.String())
+		return nil
+	case "csv":
+		// power state is reported as row without action, every action has its own row with its outcome
+		rows := [][]string{}
+		for _, a := range r.Actions {
+			rows = append(rows, []string{r.Host, r.PowerState, a.Action, string(a.Outcome), a.Error})
+		}
+		if len(rows) == 0 {
+			rows = append(rows, []string{r.Host, r.PowerState, "", "ok", ""})
+		}
+		return writeCSV(c, true, rows)
+	}
+	enc := json.NewEncoder(resultWriter{c.out})
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// writeCSV writes rows in csv format, preceded by header row if header is true
+func writeCSV(c config, header bool, rows [][]string) error {
+	if header {
+		rows = append([][]string{csvHeader}, rows...)
+	}
+	return csv.NewWriter(resultWriter{c.out}).WriteAll(rows)
+}
+
+// type yamlField describes single key of yaml mapping, mappings are kept as slices to preserve json field order
+type yamlField struct {
+	key   string
+	value interface{}
+}
+
+// writeYAML writes value as yaml document
+// value is first encoded as json, so json tags are honored the same way as in json output
+func writeYAML(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	doc, err := decodeOrdered(dec)
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	yamlNode(&sb, doc, "", "")
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// decodeOrdered decodes next json value from decoder, returning objects as []yamlField and arrays as []interface{}
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		fields := []yamlField{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			val, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, yamlField{key.(string), val})
+		}
+		_, err = dec.Token()
+		return fields, err
+	case json.Delim('['):
+		items := []interface{}{}
+		for dec.More() {
+			val, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, val)
+		}
+		_, err = dec.Token()
+		return items, err
+	}
+	return tok, nil
+}
+
+// yamlNode appends yaml representation of decoded value
+// first is written before the first line instead of indent, which allows mappings to start on the line of list dash
+func yamlNode(sb *strings.Builder, v interface{}, first, indent string) {
+	switch v := v.(type) {
+	case []yamlField:
+		if len(v) == 0 {
+			sb.WriteString(first + "{}\n")
+			return
+		}
+		for i, f := range v {
+			prefix := indent
+			if i == 0 {
+				prefix = first
+			}
+			sb.WriteString(prefix + yamlScalar(f.key) + ":")
+			yamlChild(sb, f.value, indent+"  ")
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			sb.WriteString(first + "[]\n")
+			return
+		}
+		for i, e := range v {
+			prefix := indent
+			if i == 0 {
+				prefix = first
+			}
+			yamlNode(sb, e, prefix+"- ", indent+"  ")
+		}
+	default:
+		sb.WriteString(first + yamlScalar(v) + "\n")
+	}
+}
+
+// yamlChild appends value following a mapping key, inline for scalars and empty collections and on following lines otherwise
+func yamlChild(sb *strings.Builder, v interface{}, indent string) {
+	switch e := v.(type) {
+	case []yamlField:
+		if len(e) > 0 {
+			sb.WriteString("\n")
+			yamlNode(sb, v, indent, indent)
+			return
+		}
+	case []interface{}:
+		if len(e) > 0 {
+			sb.WriteString("\n")
+			yamlNode(sb, v, indent, indent)
+			return
+		}
+	}
+	yamlNode(sb, v, " ", "")
+}
+
+// yamlScalar returns yaml representation of scalar value, quoting strings which would otherwise be ambiguous
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		if yamlPlain(v) {
+			return v
+		}
+		return strconv.Quote(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// yamlPlain returns true if string can be written in yaml without quotes
+func yamlPlain(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "null", "~", "true", "false", "yes", "no", "on", "off", "y", "n":
+		return false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return false
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return false
+	}
+	return !strings.Contains(s, ": ") && !strings.Contains(s, " #") && !strings.ContainsAny(s, "\n\t\r")
+}
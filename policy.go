@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// resolvePolicy returns local allow and deny lists of actions
+// lists set with flags take precedence over REDPOWER_ALLOW_ACTIONS and REDPOWER_DENY_ACTIONS environment variables,
+// which take precedence over allowActions and denyActions of the configuration file
+func resolvePolicy(c config, allow, deny []string) ([]string, []string, error) {
+	if len(allow) > 0 && len(deny) > 0 {
+		return allow, deny, nil
+	}
+	path, err := configPath(c.cfgfile)
+	if err != nil {
+		return nil, nil, err
+	}
+	cf, err := loadConfig(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(allow) == 0 {
+		allow = policyList("REDPOWER_ALLOW_ACTIONS", cf.AllowActions)
+	}
+	if len(deny) == 0 {
+		deny = policyList("REDPOWER_DENY_ACTIONS", cf.DenyActions)
+	}
+	return allow, deny, nil
+}
+
+// checkPolicy verifies requested actions against local allow and deny lists
+// denied action is rejected even if it is also allowed, empty allow list allows all actions
+// accycle alias performs PowerCycle, so it is matched by both names
+func checkPolicy(c config) error {
+	for _, act := range c.actions {
+		names := []string{act}
+		if strings.EqualFold(act, acCycleAction) {
+			names = append(names, "PowerCycle")
+		}
+		allowed := len(c.allow) == 0
+		for _, name := range names {
+			if containsFold(c.deny, name) {
+				return fmt.Errorf("action %s is denied by local policy", act)
+			}
+			allowed = allowed || containsFold(c.allow, name)
+		}
+		if !allowed {
+			return fmt.Errorf("action %s is not allowed by local policy", act)
+		}
+	}
+	return nil
+}
+
+// policyList returns comma separated list of actions from environment variable or configuration file list if variable is not set
+func policyList(env string, fallback []string) []string {
+	v := os.Getenv(env)
+	if v == "" {
+		return fallback
+	}
+	var list []string
+	for _, act := range strings.Split(v, ",") {
+		if act = strings.TrimSpace(act); act != "" {
+			list = append(list, act)
+		}
+	}
+	return list
+}
+
+// containsFold returns true if slice contains string, compared case-insensitively
+func containsFold(slice []string, s string) bool {
+	for _, v := range slice {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
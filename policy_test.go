@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPolicyMatchesACCycleAsPowerCycle(t *testing.T) {
+	tests := []struct {
+		args   []string
+		denied bool
+	}{
+		{[]string{"-deny-actions", "PowerCycle"}, true},
+		{[]string{"-deny-actions", "accycle"}, true},
+		{[]string{"-allow-actions", "On"}, true},
+		{[]string{"-allow-actions", "PowerCycle"}, false},
+		{[]string{"-allow-actions", "accycle"}, false},
+	}
+	for _, tt := range tests {
+		var resets int32
+		wrap := func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "ComputerSystem.Reset") {
+					atomic.AddInt32(&resets, 1)
+				}
+				h.ServeHTTP(w, r)
+			})
+		}
+		m, srv := newTestServer(t, "u", "p", wrap)
+		m.power = "On"
+		args := append([]string{"-user", "u", "-pass", "p", "-quiet", "-action", "accycle"}, tt.args...)
+		_, _, err := runTest(t, srv, args...)
+		denied := err != nil && strings.Contains(err.Error(), "local policy")
+		if denied != tt.denied {
+			t.Errorf("%v: accycle returned %v, want denied %t", tt.args, err, tt.denied)
+		}
+		if n := atomic.LoadInt32(&resets); (n > 0) == tt.denied {
+			t.Errorf("%v: %d reset requests sent, want denied %t", tt.args, n, tt.denied)
+		}
+	}
+}
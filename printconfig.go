@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// type configSources records where configuration values come from, indexed by flag name
+// values not listed are flag defaults
+type configSources map[string]string
+
+// note records source of host and credentials changed between old and new config
+func (s configSources) note(old, new config, source string) {
+	if old.host != new.host {
+		s["host"] = source
+	}
+	if old.user != new.user {
+		s["user"] = source
+	}
+	if old.pass != new.pass {
+		s["pass"] = source
+	}
+}
+
+// secretFlags lists flags which values are never printed
+var secretFlags = []string{"pass", "token", "downstream-pass", "inventory-auth", "webhook-auth"}
+
+// envFlags maps flags with defaults taken from environment to environment variable names
+var envFlags = map[string]string{
+	"token":          "REDPOWER_TOKEN",
+	"inventory-auth": "REDPOWER_INVENTORY_AUTH",
+	"webhook-auth":   "REDPOWER_WEBHOOK_AUTH",
+	"allow-actions":  "REDPOWER_ALLOW_ACTIONS",
+	"deny-actions":   "REDPOWER_DENY_ACTIONS",
+}
+
+// printConfig prints effective value and source of every flag for -print-config, secrets are redacted
+// host and credentials are printed as resolved from profile, inventory and .netrc
+func printConfig(c config, flags *flag.FlagSet, sources configSources, allow, deny []string) error {
+	allow, deny, err := resolvePolicy(c, allow, deny)
+	if err != nil {
+		return err
+	}
+	effective := map[string]string{
+		"host":          c.host,
+		"user":          c.user,
+		"pass":          c.pass,
+		"allow-actions": strings.Join(allow, ","),
+		"deny-actions":  strings.Join(deny, ","),
+	}
+	w := tabwriter.NewWriter(resultWriter{c.out}, 0, 8, 2, ' ', 0)
+	if !c.quiet {
+		fmt.Fprintln(w, "FLAG\tVALUE\tSOURCE")
+	}
+	flags.VisitAll(func(f *flag.Flag) {
+		value, ok := effective[f.Name]
+		if !ok {
+			value = f.Value.String()
+		}
+		source := sources[f.Name]
+		if env := envFlags[f.Name]; source == "" && env != "" && os.Getenv(env) != "" {
+			source = "environment " + env
+		}
+		if source == "" && (f.Name == "allow-actions" || f.Name == "deny-actions") && value != "" {
+			source = "config file"
+		}
+		if source == "" {
+			source = "default"
+		}
+		if value != "" && contains(secretFlags, f.Name) {
+			value = redacted
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", f.Name, value, source)
+	})
+	return w.Flush()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// type printer receives all output of the program, so output of every host can be held back and printed together in batch mode
+// Info receives informational messages (suppressed with -quiet), Debug receives -debug and -trace output,
+// Error receives errors, warnings and summaries and Result receives requested data (like power state)
+// messages are formatted like with fmt.Printf and include trailing newline when line is complete
+type printer interface {
+	Info(format string, args ...interface{})
+	Debug(format string, args ...interface{})
+	Error(format string, args ...interface{})
+	Result(format string, args ...interface{})
+}
+
+// type streamPrinter is printer used by the command line, writing info and results to stdout and the rest to stderr
+type streamPrinter struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Info writes informational message to stdout
+func (p *streamPrinter) Info(format string, args ...interface{}) {
+	fmt.Fprintf(p.stdout, format, args...)
+}
+
+// Debug writes debug message to stderr
+func (p *streamPrinter) Debug(format string, args ...interface{}) {
+	fmt.Fprintf(p.stderr, format, args...)
+}
+
+// Error writes error message to stderr
+func (p *streamPrinter) Error(format string, args ...interface{}) {
+	fmt.Fprintf(p.stderr, format, args...)
+}
+
+// Result writes result to stdout
+func (p *streamPrinter) Result(format string, args ...interface{}) {
+	fmt.Fprintf(p.stdout, format, args...)
+}
+
+// type recordedMessage describes message held back by recorder
+type recordedMessage struct {
+	kind string
+	text string
+}
+
+// type recorder is printer holding back all messages until they are replayed to another printer or discarded
+// it is used to keep output of every host together in batch mode and with -quiet-on-success
+type recorder struct {
+	messages []recordedMessage
+}
+
+// Info records informational message
+func (r *recorder) Info(format string, args ...interface{}) {
+	r.record("info", format, args)
+}
+
+// Debug records debug message
+func (r *recorder) Debug(format string, args ...interface{}) {
+	r.record("debug", format, args)
+}
+
+// Error records error message
+func (r *recorder) Error(format string, args ...interface{}) {
+	r.record("error", format, args)
+}
+
+// Result records result
+func (r *recorder) Result(format string, args ...interface{}) {
+	r.record("result", format, args)
+}
+
+// record appends formatted message of specified kind
+func (r *recorder) record(kind, format string, args []interface{}) {
+	r.messages = append(r.messages, recordedMessage{kind, fmt.Sprintf(format, args...)})
+}
+
+// drop discards recorded messages of specified kind
+func (r *recorder) drop(kind string) {
+	kept := r.messages[:0]
+	for _, m := range r.messages {
+		if m.kind != kind {
+			kept = append(kept, m)
+		}
+	}
+	r.messages = kept
+}
+
+// replay passes recorded messages to another printer in original order
+func (r *recorder) replay(p printer) {
+	for _, m := range r.messages {
+		switch m.kind {
+		case "info":
+			p.Info("%s", m.text)
+		case "debug":
+			p.Debug("%s", m.text)
+		case "error":
+			p.Error("%s", m.text)
+		case "result":
+			p.Result("%s", m.text)
+		}
+	}
+}
+
+// type resultWriter adapts printer to io.Writer passing everything written as results
+// it allows encoders and table writers to print through the printer
+type resultWriter struct {
+	p printer
+}
+
+// Write passes written bytes as result
+func (w resultWriter) Write(b []byte) (int, error) {
+	w.p.Result("%s", b)
+	return len(b), nil
+}
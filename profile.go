@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// type configFile describes configuration file with named host profiles, groups of profiles and local action policy
+type configFile struct {
+	Profiles     map[string]profile  `json:"profiles"`
+	Groups       map[string][]string `json:"groups,omitempty"`
+	AllowActions []string            `json:"allowActions,omitempty"`
+	DenyActions  []string            `json:"denyActions,omitempty"`
+}
+
+// type profile describes settings of single host used as defaults for flags not set on command line
+// allowed actions are informational only and are not used when profile is applied
+type profile struct {
+	Host           string   `json:"host"`
+	User           string   `json:"user,omitempty"`
+	PassFile       string   `json:"passFile,omitempty"`
+	Insecure       bool     `json:"insecure,omitempty"`
+	SystemURL      string   `json:"systemUrl,omitempty"`
+	Vendor         string   `json:"vendor,omitempty"`
+	AllowedActions []string `json:"allowedActions,omitempty"`
+}
+
+// configPath returns path of the configuration file specified with -config or default one in user config directory
+func configPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "redpower", "config.json"), nil
+}
+
+// loadConfig reads configuration file, missing file results in empty configuration
+func loadConfig(path string) (configFile, error) {
+	cf := configFile{Profiles: map[string]profile{}}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cf, nil
+	}
+	if err != nil {
+		return cf, fmt.Errorf("cannot read config file: %s", err)
+	}
+	if err := json.Unmarshal(b, &cf); err != nil {
+		return cf, fmt.Errorf("cannot parse config file %s: %s", path, err)
+	}
+	if cf.Profiles == nil {
+		cf.Profiles = map[string]profile{}
+	}
+	return cf, nil
+}
+
+// saveConfig writes configuration file readable only by the owner
+func saveConfig(path string, cf configFile) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(cf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0600)
+}
+
+// applyProfile sets flags from named profile unless they were set explicitly on command line
+func applyProfile(flags *flag.FlagSet, path, name string) error {
+	cf, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	p, ok := cf.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %s not found in config file %s", name, path)
+	}
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	values := map[string]string{
+		"host":       p.Host,
+		"user":       p.User,
+		"pass-file":  p.PassFile,
+		"system-url": p.SystemURL,
+		"vendor":     p.Vendor,
+	}
+	if p.Insecure {
+		values["insecure"] = strconv.FormatBool(p.Insecure)
+	}
+	for name, value := range values {
+		if value == "" || set[name] {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s in profile: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// groupMembers returns names and profiles of members of the group requested with -group
+// profile settings overridden on command line are cleared, so they are not applied to members
+func groupMembers(c config, sources configSources) ([]string, map[string]profile, error) {
+	path, err := configPath(c.cfgfile)
+	if err != nil {
+		return nil, nil, err
+	}
+	cf, err := loadConfig(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	names, ok := cf.Groups[c.group]
+	switch {
+	case !ok:
+		return nil, nil, fmt.Errorf("group %s not found in config file %s", c.group, path)
+	case len(names) == 0:
+		return nil, nil, fmt.Errorf("group %s in config file %s has no members", c.group, path)
+	}
+	members := map[string]profile{}
+	for _, name := range names {
+		p, ok := cf.Profiles[name]
+		switch {
+		case !ok:
+			return nil, nil, fmt.Errorf("group %s refers to profile %s not found in config file %s", c.group, name, path)
+		case p.Host == "":
+			return nil, nil, fmt.Errorf("profile %s of group %s has no host", name, c.group)
+		case members[name].Host != "":
+			return nil, nil, fmt.Errorf("profile %s is listed more than once in group %s", name, c.group)
+		}
+		if sources["user"] != "" {
+			p.User = ""
+		}
+		if sources["pass"] != "" || sources["pass-file"] != "" {
+			p.PassFile = ""
+		}
+		if sources["system-url"] != "" {
+			p.SystemURL = ""
+		}
+		if sources["vendor"] != "" {
+			p.Vendor = ""
+		}
+		members[name] = p
+	}
+	return names, members, nil
+}
+
+// memberConfig returns config for single member of the group with settings of its profile applied
+func memberConfig(c config, p profile) (config, error) {
+	c.host = p.Host
+	if p.User != "" {
+		c.user = p.User
+	}
+	if p.PassFile != "" {
+		b, err := ioutil.ReadFile(p.PassFile)
+		if err != nil {
+			return c, fmt.Errorf("cannot read password file: %s", err)
+		}
+		c.pass = strings.TrimRight(string(b), "\r\n")
+	}
+	if p.SystemURL != "" {
+		c.sysurl = p.SystemURL
+	}
+	if p.Vendor != "" {
+		c.vendor = p.Vendor
+	}
+	// certificate verification is part of the client, so insecure member needs its own client
+	if p.Insecure && !c.insecure && c.scheme == "https" {
+		c.insecure = true
+		c.client = newClient(c)
+	}
+	return c, nil
+}
+
+// insecureMember returns true if any member of the group has insecure profile
+func insecureMember(members map[string]profile) bool {
+	for _, p := range members {
+		if p.Insecure {
+			return true
+		}
+	}
+	return false
+}
+
+// exportProfile discovers the system and saves its profile under name specified with -export-profile
+// password is never saved, use pass-file in the profile to provide it
+func exportProfile(c config) error {
+	sys, err := getSystem(c)
+	if err != nil {
+		return err
+	}
+	allowed, err := allowedActions(c, sys)
+	if err != nil {
+		return err
+	}
+	vendor := c.vendor
+	if vendor == "generic" {
+		vendor = detectVendor(sys)
+	}
+	path, err := configPath(c.cfgfile)
+	if err != nil {
+		return err
+	}
+	cf, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	p := cf.Profiles[c.export]
+	p.Host = c.host
+	p.User = c.user
+	// lab default from environment is not a property of the host
+	p.Insecure = c.insecure && !c.envinsec
+	p.SystemURL = sys.OdataID
+	p.Vendor = vendor
+	p.AllowedActions = allowed
+	cf.Profiles[c.export] = p
+	if err := saveConfig(path, cf); err != nil {
+		return fmt.Errorf("cannot write config file: %s", err)
+	}
+	if !c.quiet {
+		c.out.Info("host: %s profile %s saved to %s\n", c.host, c.export, path)
+	}
+	return nil
+}
+
+// detectVendor returns vendor owning all OEM actions of the system or generic if it cannot be determined
+func detectVendor(sys system) string {
+	detected := "generic"
+	for _, oa := range oemActions(sys, "generic") {
+		vendor := "generic"
+		for v := range vendorNamespaces {
+			if vendorMatches(v, oa.namespace) {
+				vendor = v
+			}
+		}
+		if vendor == "generic" || (detected != "generic" && detected != vendor) {
+			return "generic"
+		}
+		detected = vendor
+	}
+	return detected
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// known redfish power states, always reported in prometheus output so each state has its own series
+var powerStates = []string{"On", "Off", "PoweringOn", "PoweringOff", "Paused"}
+
+// type hostMetrics describes power state of the host and outcome of its retrieval reported in prometheus output
+type hostMetrics struct {
+	host     string
+	state    string
+	err      error
+	duration time.Duration
+}
+
+// getMetrics prints current power state for specified host in prometheus text exposition format
+// scrape metrics are printed even if power state cannot be retrieved
+// in batch mode metrics are collected instead, so all hosts are printed together when they are done
+func getMetrics(c config) error {
+	start := time.Now()
+	state, err := getPowerState(c)
+	m := hostMetrics{c.host, state, err, time.Since(start)}
+	if c.pushgw != "" {
+		pushMetrics(c, metrics([]hostMetrics{m}))
+	}
+	if c.samples != nil {
+		*c.samples = append(*c.samples, m)
+		return err
+	}
+	c.out.Result("%s", metrics([]hostMetrics{m}))
+	return err
+}
+
+// metrics returns power state and scrape metrics of the hosts in prometheus text exposition format
+// every metric family is written once with samples of all hosts, power state is omitted for hosts where it could not be retrieved
+func metrics(hosts []hostMetrics) string {
+	var b strings.Builder
+	header := true
+	for _, m := range hosts {
+		if m.err != nil {
+			continue
+		}
+		if header {
+			b.WriteString("# HELP redpower_power_state Current power state of the system.\n")
+			b.WriteString("# TYPE redpower_power_state gauge\n")
+			header = false
+		}
+		states := powerStates
+		if !contains(states, m.state) {
+			states = append(states, m.state)
+		}
+		for _, s := range states {
+			value := 0
+			if s == m.state {
+				value = 1
+			}
+			fmt.Fprintf(&b, "redpower_power_state{host=\"%s\",state=\"%s\"} %d\n", escapeLabel(m.host), escapeLabel(s), value)
+		}
+	}
+	b.WriteString("# HELP redpower_scrape_success Whether the power state was retrieved successfully.\n")
+	b.WriteString("# TYPE redpower_scrape_success gauge\n")
+	for _, m := range hosts {
+		success := 0
+		if m.err == nil {
+			success = 1
+		}
+		fmt.Fprintf(&b, "redpower_scrape_success{host=\"%s\"} %d\n", escapeLabel(m.host), success)
+	}
+	b.WriteString("# HELP redpower_scrape_duration_seconds Time spent retrieving the power state.\n")
+	b.WriteString("# TYPE redpower_scrape_duration_seconds gauge\n")
+	for _, m := range hosts {
+		fmt.Fprintf(&b, "redpower_scrape_duration_seconds{host=\"%s\"} %g\n", escapeLabel(m.host), m.duration.Seconds())
+	}
+	return b.String()
+}
+
+// escapeLabel escapes backslash, double quote and new line in prometheus label value
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// contains returns true if slice contains specified string
+func contains(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// pushMetrics pushes metrics of the host to -pushgateway replacing metrics of its group identified by -job and host
+// delivery failures are printed as warnings only, so they never change outcome of the run
+func pushMetrics(c config, metrics string) {
+	if err := putMetrics(c, metrics); err != nil {
+		c.out.Error("warning: pushgateway delivery failed: %s\n", err)
+	}
+}
+
+// putMetrics sends http PUT request with metrics in text exposition format to pushgateway url of the host group
+func putMetrics(c config, metrics string) error {
+	url := strings.TrimRight(c.pushgw, "/") + "/metrics" + groupingKey("job", c.job) + groupingKey("host", c.host)
+	req, err := http.NewRequest("PUT", url, strings.NewReader(metrics))
+	if err != nil {
+		return fmt.Errorf("invalid -pushgateway: %s", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	req.Header.Set("User-Agent", c.agent)
+	client := &http.Client{Timeout: time.Second * time.Duration(c.timeout)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status code: %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	tracef(c, "pushgateway: metrics pushed to %s", url)
+	return nil
+}
+
+// groupingKey returns pushgateway url path element of grouping key label
+// values which are empty or contain slash are base64 encoded as required by pushgateway
+func groupingKey(name, value string) string {
+	if value == "" || strings.Contains(value, "/") {
+		return "/" + name + "@base64/" + base64.URLEncoding.EncodeToString([]byte(value))
+	}
+	return "/" + name + "/" + url.PathEscape(value)
+}
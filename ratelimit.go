@@ -0,0 +1,34 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// type rateLimiter spaces requests of the whole run evenly according to -rate, it is shared by all hosts in batch mode
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter returns limiter allowing specified number of requests per second
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait reserves next free slot and waits until it starts or until the run is cancelled
+func (l *rateLimiter) wait(c config) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	d := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	return sleep(c, d)
+}
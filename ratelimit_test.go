@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSpacesConcurrentRequests(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	l := newRateLimiter(float64(time.Second / interval))
+	var mu sync.Mutex
+	var times []time.Time
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.wait(config{}); err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			times = append(times, time.Now())
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	// every request waits for its own slot, so the last one starts after all slots before it
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	if total := times[len(times)-1].Sub(start); total < time.Duration(len(times)-1)*interval {
+		t.Errorf("%d concurrent requests started within %s, want at least %s", len(times), total, time.Duration(len(times)-1)*interval)
+	}
+}
+
+func TestRateLimitsRequestsOfRun(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	_, srv := newTestServer(t, "u", "p", func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			times = append(times, time.Now())
+			mu.Unlock()
+			h.ServeHTTP(w, r)
+		})
+	})
+	if _, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-quiet", "-rate", "10", "-action", "On"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(times) < 3 {
+		t.Fatalf("%d requests received, want at least 3", len(times))
+	}
+	if total := times[len(times)-1].Sub(times[0]); total < time.Duration(len(times)-1)*75*time.Millisecond {
+		t.Errorf("%d requests with -rate 10 received within %s", len(times), total)
+	}
+}
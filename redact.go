@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redacted replaces credentials in debug and trace output
+const redacted = "****"
+
+// sensitiveHeaders lists request headers which values are never printed
+// header carrying downstream credentials is configurable, so it is checked separately
+var sensitiveHeaders = []string{"Authorization", "X-Auth-Token"}
+
+// passwordField matches value of Password property in json body, like body of session request
+var passwordField = regexp.MustCompile(`(?i)("password"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// sensitiveHeader returns true if value of the header carries credentials
+func sensitiveHeader(c config, name string) bool {
+	for _, h := range sensitiveHeaders {
+		if strings.EqualFold(name, h) {
+			return true
+		}
+	}
+	return c.dsheader != "" && strings.EqualFold(name, c.dsheader)
+}
+
+// redactHeader returns value of the header or **** if the header carries credentials
+func redactHeader(c config, name, value string) string {
+	if sensitiveHeader(c, name) {
+		return redacted
+	}
+	return value
+}
+
+// redactBody returns json body with value of every Password property replaced with ****
+// other content is returned unchanged, so redaction never garbles the output
+func redactBody(body string) string {
+	return passwordField.ReplaceAllString(body, `$1"`+redacted+`"`)
+}
+
+// printRequest prints request method, url and headers if debug is enabled
+// values of sensitive headers are replaced with ****
+func printRequest(c config, req *http.Request) {
+	if !c.debug {
+		return
+	}
+	c.out.Debug("request: %s %s\n", req.Method, req.URL.String())
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.out.Debug("%s: %s\n", name, redactHeader(c, name, strings.Join(req.Header[name], ", ")))
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestDebugOutputRedactsCredentials(t *testing.T) {
+	const user, pass = "admin", "s3cr3t-Pw"
+	_, srv := newTestServer(t, user, pass, nil)
+	_, stderr, err := runTest(t, srv, "-user", user, "-pass", pass, "-debug", "-trace", "-get")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, secret := range []string{pass, base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))} {
+		if strings.Contains(stderr, secret) {
+			t.Errorf("debug output contains credentials %q:\n%s", secret, stderr)
+		}
+	}
+	if !strings.Contains(stderr, "Authorization: "+redacted) {
+		t.Errorf("debug output does not contain redacted Authorization header:\n%s", stderr)
+	}
+}
+
+func TestDebugOutputNotGarbledByShortPassword(t *testing.T) {
+	_, srv := newTestServer(t, "u", "p", nil)
+	_, stderr, err := runTest(t, srv, "-user", "u", "-pass", "p", "-debug", "-get")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, want := range []string{"request: GET http://", "Accept: application/json"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("debug output does not contain %q:\n%s", want, stderr)
+		}
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"UserName":"admin","Password":"s3cr3t"}`, `{"UserName":"admin","Password":"****"}`},
+		{`{"UserName": "admin", "password" : "with \"quote\""}`, `{"UserName": "admin", "password" : "****"}`},
+		{`{"PowerState":"On","Id":"s3cr3t"}`, `{"PowerState":"On","Id":"s3cr3t"}`},
+		{`not json`, `not json`},
+	}
+	for _, tt := range tests {
+		if got := redactBody(tt.body); got != tt.want {
+			t.Errorf("redactBody(%s) = %s, want %s", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestRedactHeader(t *testing.T) {
+	c := config{dsheader: "X-Auth-Downstream"}
+	for _, name := range []string{"Authorization", "x-auth-token", "X-Auth-Downstream"} {
+		if got := redactHeader(c, name, "secret"); got != redacted {
+			t.Errorf("redactHeader(%s) = %s, want %s", name, got, redacted)
+		}
+	}
+	if got := redactHeader(c, "Accept", "application/json"); got != "application/json" {
+		t.Errorf("redactHeader(Accept) = %s, want application/json", got)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// type hostReport describes outcome of batch run for single host
+type hostReport struct {
+	Host         string  `json:"host"`
+	SystemURL    string  `json:"systemUrl,omitempty"`
+	Action       string  `json:"action,omitempty"`
+	InitialState string  `json:"initialState,omitempty"`
+	FinalState   string  `json:"finalState,omitempty"`
+	Status       string  `json:"status"`
+	Error        string  `json:"error,omitempty"`
+	Duration     float64 `json:"durationSeconds"`
+}
+
+// type batchReport describes batch run written to -report file
+// complete is false until all hosts are done, so report of interrupted run can be recognized
+type batchReport struct {
+	Started   time.Time    `json:"started"`
+	Finished  *time.Time   `json:"finished,omitempty"`
+	Complete  bool         `json:"complete"`
+	Total     int          `json:"total"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Skipped   int          `json:"skipped"`
+	Hosts     []hostReport `json:"hosts"`
+}
+
+// add appends host outcome and updates totals
+func (r *batchReport) add(h hostReport) {
+	r.Hosts = append(r.Hosts, h)
+	switch h.Status {
+	case "ok":
+		r.Succeeded++
+	case "skipped":
+		r.Skipped++
+	default:
+		r.Failed++
+	}
+}
+
+// write replaces report file with current state of the report
+// file is written to temporary file first, so it always contains complete json document
+func (r *batchReport) write(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".redpower-report-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
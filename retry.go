@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maximum delay between retries when server does not specify Retry-After
+const maxBackoff = 30 * time.Second
+
+// retryDelay returns delay before next attempt and true if failed request should be retried
+// requests rejected with 429 or 503 are retried after delay requested in Retry-After header (capped by -timeout)
+// other transient errors are retried with exponential backoff, but only for idempotent requests
+func retryDelay(c config, req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	backoff := time.Second << uint(attempt)
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	idempotent := req.Method == "GET" || req.Method == "DELETE"
+	switch {
+	case err != nil:
+		return backoff, idempotent
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if limit := time.Second * time.Duration(c.timeout); d > limit {
+				d = limit
+			}
+			return d, true
+		}
+		return backoff, true
+	case resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusGatewayTimeout:
+		return backoff, idempotent
+	}
+	return 0, false
+}
+
+// jitterRand randomizes poll intervals, it is locked as it is shared by all workers in batch mode
+var jitterRand = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// jitter returns duration randomly changed by up to 20% in either direction
+func jitter(d time.Duration) time.Duration {
+	spread := int64(d / 5)
+	if spread <= 0 {
+		return d
+	}
+	jitterRand.Lock()
+	defer jitterRand.Unlock()
+	return d - time.Duration(spread) + time.Duration(jitterRand.Int63n(2*spread+1))
+}
+
+// parseRetryAfter parses Retry-After header value in seconds or HTTP-date format and returns delay relative to now
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Second * time.Duration(secs), true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// sleep pauses for specified duration or until context of the config is done, returning context error in the latter case
+func sleep(c config, d time.Duration) error {
+	if c.ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"5", 5 * time.Second, true},
+		{"Wed, 01 Jan 2020 12:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 01 Jan 2020 11:59:00 GMT", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %s, %t, want %s, %t", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRetryDelayHonorsRetryAfter(t *testing.T) {
+	c := config{timeout: 10}
+	get, _ := http.NewRequest("GET", "http://bmc/redfish/v1", nil)
+	post, _ := http.NewRequest("POST", "http://bmc/redfish/v1", nil)
+	tests := []struct {
+		req        *http.Request
+		status     int
+		retryAfter string
+		want       time.Duration
+		retry      bool
+	}{
+		{get, http.StatusTooManyRequests, "3", 3 * time.Second, true},
+		{post, http.StatusTooManyRequests, "3", 3 * time.Second, true},
+		{get, http.StatusServiceUnavailable, "7", 7 * time.Second, true},
+		// capped by -timeout
+		{get, http.StatusTooManyRequests, "120", 10 * time.Second, true},
+		// exponential backoff without Retry-After
+		{get, http.StatusTooManyRequests, "", 2 * time.Second, true},
+		{get, http.StatusInternalServerError, "3", 0, false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		if tt.retryAfter != "" {
+			resp.Header.Set("Retry-After", tt.retryAfter)
+		}
+		got, retry := retryDelay(c, tt.req, resp, nil, 1)
+		if got != tt.want || retry != tt.retry {
+			t.Errorf("%s %d Retry-After %q: delay %s, %t, want %s, %t", tt.req.Method, tt.status, tt.retryAfter, got, retry, tt.want, tt.retry)
+		}
+	}
+}
+
+func TestRequestRetriedAfterRetryAfter(t *testing.T) {
+	var rejected int32
+	_, srv := newTestServer(t, "u", "p", func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.CompareAndSwapInt32(&rejected, 0, 1) {
+				w.Header().Set("Retry-After", "1")
+				mockError(w, http.StatusTooManyRequests, "Base.1.8.GeneralError", "too many requests")
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	start := time.Now()
+	if _, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-quiet", "-retries", "1", "-get"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("request retried after %s, want at least 1s requested in Retry-After", elapsed)
+	}
+}
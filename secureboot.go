@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// type secureBoot describes (partial) redfish secure boot resource of the system
+type secureBoot struct {
+	OdataID               string `json:"@odata.id"`
+	Etag                  string `json:"@odata.etag"`
+	SecureBootEnable      *bool  `json:"SecureBootEnable"`
+	SecureBootCurrentBoot string `json:"SecureBootCurrentBoot"`
+	SecureBootMode        string `json:"SecureBootMode"`
+}
+
+// getSecureBoot returns secure boot resource of the system
+func getSecureBoot(c config, sys system) (secureBoot, error) {
+	var sb secureBoot
+	url := sys.SecureBoot.OdataID
+	if url == "" {
+		url = joinPath(sys.OdataID, "SecureBoot")
+	}
+	b, err := redfishGet(c, hostURL(c, url))
+	var rerr *redfishError
+	if errors.As(err, &rerr) && rerr.statusCode == http.StatusNotFound {
+		return sb, fmt.Errorf("system does not provide secure boot resource")
+	}
+	if err != nil {
+		return sb, err
+	}
+	if err := json.Unmarshal(b, &sb); err != nil {
+		return sb, fmt.Errorf("cannot parse secure boot resource: %s", err)
+	}
+	if sb.OdataID == "" {
+		sb.OdataID = url
+	}
+	return sb, nil
+}
+
+// printSecureBoot prints whether secure boot is enabled and if it is used for current boot
+func printSecureBoot(c config) error {
+	sys, err := getSystem(c)
+	if err != nil {
+		return err
+	}
+	sb, err := getSecureBoot(c, sys)
+	if err != nil {
+		return err
+	}
+	enabled := "unknown"
+	if sb.SecureBootEnable != nil {
+		enabled = onOff(*sb.SecureBootEnable)
+	}
+	if !c.quiet {
+		c.out.Info("host: %s secure boot: ", c.host)
+	}
+	c.out.Result("%s (current boot: %s)\n", enabled, sb.SecureBootCurrentBoot)
+	return nil
+}
+
+// setSecureBoot enables or disables secure boot as requested with -secureboot
+// change usually takes effect after reset, which is performed if actions are requested as well
+func setSecureBoot(c config) error {
+	sys, err := getSystem(c)
+	if err != nil {
+		return err
+	}
+	sb, err := getSecureBoot(c, sys)
+	if err != nil {
+		return err
+	}
+	enable := c.secboot == "on"
+	url := hostURL(c, sb.OdataID)
+	tracef(c, "secure boot target: %s enable: %t", url, enable)
+	if _, _, err := redfishPatch(c, url, map[string]interface{}{"SecureBootEnable": enable}, sb.Etag); err != nil {
+		return err
+	}
+	if !c.quiet {
+		c.out.Info("host: %s secure boot set %s\n", c.host, c.secboot)
+	}
+	if len(c.actions) > 0 {
+		return action(c)
+	}
+	if !c.quiet {
+		c.out.Info("note: most BMCs apply secure boot change only after the system is reset\n")
+	}
+	return nil
+}
+
+// onOff returns on or off for boolean value
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
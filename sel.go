@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// type logEntry describes (partial) redfish log entry
+type logEntry struct {
+	Created   string `json:"Created"`
+	Severity  string `json:"Severity"`
+	Message   string `json:"Message"`
+	MessageID string `json:"MessageId"`
+}
+
+// sel prints system event log entries filtered by -sel-severity, -sel-since or -sel-after and sorted by creation time according to -sel-order
+// currently only hosts with single computer system in redfish systems collection are supported
+func sel(c config) error {
+	after, err := selWindow(c)
+	if err != nil {
+		return err
+	}
+	sys, err := getSystem(c)
+	if err != nil {
+		return err
+	}
+	if sys.LogServices.OdataID == "" {
+		return fmt.Errorf("system does not provide log services")
+	}
+	url, err := getSELEntriesURL(c, sys.LogServices.OdataID)
+	if err != nil {
+		return err
+	}
+	entries, err := getLogEntries(c, url)
+	if err != nil {
+		return err
+	}
+
+	// filter by severity threshold and creation time and sort by creation time
+	// entries without valid creation time cannot be placed in the time window, so they are skipped
+	threshold := severityLevel(c.selsev)
+	var filtered []logEntry
+	undated := 0
+	for _, e := range entries {
+		if threshold != 0 && severityLevel(e.Severity) < threshold {
+			continue
+		}
+		if !after.IsZero() {
+			created, err := time.Parse(time.RFC3339, e.Created)
+			if err != nil {
+				undated++
+				continue
+			}
+			if !created.After(after) {
+				continue
+			}
+		}
+		filtered = append(filtered, e)
+	}
+	if undated > 0 {
+		c.out.Error("warning: %d SEL entries without valid creation time skipped\n", undated)
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339, filtered[i].Created)
+		tj, _ := time.Parse(time.RFC3339, filtered[j].Created)
+		if c.selorder == "desc" {
+			return ti.After(tj)
+		}
+		return ti.Before(tj)
+	})
+
+	if !c.quiet {
+		c.out.Info("host: %s system event log:\n", c.host)
+	}
+	w := tabwriter.NewWriter(resultWriter{c.out}, 0, 8, 2, ' ', 0)
+	if !c.quiet {
+		fmt.Fprintln(w, "CREATED\tSEVERITY\tMESSAGE")
+	}
+	for _, e := range filtered {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Created, e.Severity, e.Message)
+	}
+	return w.Flush()
+}
+
+// selWindow returns time after which SEL entries must be created to be printed or zero time if all entries are printed
+func selWindow(c config) (time.Time, error) {
+	switch {
+	case c.selsince > 0:
+		return time.Now().Add(-c.selsince), nil
+	case c.selafter != "":
+		after, err := time.Parse(time.RFC3339, c.selafter)
+		if err != nil {
+			return after, fmt.Errorf("invalid -sel-after time (RFC3339 like 2024-01-02T15:04:05Z expected): %s", err)
+		}
+		return after, nil
+	}
+	return time.Time{}, nil
+}
+
+// getSELEntriesURL returns URL of entries collection of the SEL log service found in specified log services collection
+func getSELEntriesURL(c config, path string) (string, error) {
+	b, err := redfishGet(c, hostURL(c, path))
+	if err != nil {
+		return "", err
+	}
+	services, err := parseRedfishCollection(b)
+	if err != nil {
+		return "", err
+	}
+	for _, service := range services {
+		b, err := redfishGet(c, hostURL(c, service))
+		if err != nil {
+			return "", err
+		}
+		var ls struct {
+			ID      string `json:"Id"`
+			Entries struct {
+				OdataID string `json:"@odata.id"`
+			} `json:"Entries"`
+		}
+		if err := json.Unmarshal(b, &ls); err != nil {
+			return "", err
+		}
+		if strings.Contains(strings.ToLower(ls.ID), "sel") && ls.Entries.OdataID != "" {
+			return hostURL(c, ls.Entries.OdataID), nil
+		}
+	}
+	return "", fmt.Errorf("no SEL log service found")
+}
+
+// getLogEntries returns all log entries from specified entries collection, following next page links
+func getLogEntries(c config, url string) ([]logEntry, error) {
+	var entries []logEntry
+	for url != "" {
+		b, err := redfishGet(c, url)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Members  []logEntry `json:"Members"`
+			NextLink string     `json:"Members@odata.nextLink"`
+		}
+		if err := json.Unmarshal(b, &page); err != nil {
+			return nil, err
+		}
+		entries = append(entries, page.Members...)
+		url = ""
+		if page.NextLink != "" {
+			url = hostURL(c, page.NextLink)
+		}
+	}
+	return entries, nil
+}
+
+// severityLevel returns ordered level of redfish severity (case-insensitive) or -1 if severity is unknown
+func severityLevel(severity string) int {
+	switch strings.ToLower(severity) {
+	case "ok":
+		return 0
+	case "warning":
+		return 1
+	case "critical":
+		return 2
+	}
+	return -1
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// type session describes redfish session used to authenticate requests, it is shared by all copies of config
+// so session created after the token was rejected is used by all following requests
+type session struct {
+	mu       sync.Mutex
+	token    string
+	location string
+}
+
+// current returns token of the session
+func (s *session) current() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+// canRelogin returns true if request rejected with 401 can be retried with new session created using -user and -pass
+func canRelogin(c config, req *http.Request) bool {
+	return !c.norelog && c.sess != nil && c.user != "" && c.pass != "" && req.Header.Get("X-Auth-Token") != ""
+}
+
+// relogin replaces rejected session token with token of new session
+// nothing is done if the token was already replaced by concurrent request
+func (s *session) relogin(c config, rejected string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != rejected {
+		return nil
+	}
+	token, location, err := createSession(c)
+	if err != nil {
+		return fmt.Errorf("session token was rejected and new session cannot be created - %w", err)
+	}
+	tracef(c, "session token rejected, new session created: %s", location)
+	s.token, s.location = token, location
+	return nil
+}
+
+// closeSession deletes session created by redpower, session of token specified with -token is left as is
+func closeSession(c config) {
+	if c.sess == nil {
+		return
+	}
+	c.sess.mu.Lock()
+	location := c.sess.location
+	c.sess.mu.Unlock()
+	if location == "" {
+		return
+	}
+	if err := deleteSession(c, location); err != nil {
+		c.out.Error("error: cannot delete session: %s\n", err)
+	}
+}
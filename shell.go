@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// shell runs interactive shell executing commands read from stdin using single redfish session
+// session is deleted when shell exits, session of token specified with -token is used as is
+// new session replaces expired one unless -no-relogin is set
+func shell(c config) error {
+	if c.token != "" {
+		return shellLoop(c)
+	}
+	token, location, err := createSession(c)
+	if err != nil {
+		return err
+	}
+	c.token = token
+	c.sess = &session{token: token, location: location}
+	defer closeSession(c)
+	return shellLoop(c)
+}
+
+// shellLoop executes commands read from stdin until quit or end of input
+func shellLoop(c config) error {
+	scanner := bufio.NewScanner(c.stdin)
+	for {
+		c.out.Info("%s> ", c.host)
+		if !scanner.Scan() {
+			c.out.Info("\n")
+			break
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		// every command reads current state of the host
+		c.memo.invalidate()
+		var err error
+		switch cmd := fields[0]; {
+		case (cmd == "quit" || cmd == "exit") && len(fields) == 1:
+			return nil
+		case cmd == "help" && len(fields) == 1:
+			c.out.Info("commands: get, list, action ACTION, raw PATH, quit\n")
+		case cmd == "get" && len(fields) == 1:
+			err = get(c)
+		case cmd == "list" && len(fields) == 1:
+			err = list(c)
+		case cmd == "action" && len(fields) == 2:
+			ac := c
+			ac.actions = actionList{fields[1]}
+			err = action(ac)
+		case cmd == "raw" && len(fields) == 2:
+			err = raw(c, fields[1])
+		default:
+			err = fmt.Errorf("unknown command or wrong number of arguments: %s (try help)", scanner.Text())
+		}
+		if err != nil {
+			c.out.Error("error: %s\n", err)
+		}
+	}
+	return scanner.Err()
+}
+
+// raw prints response body of http GET request for specified redfish path or only its property selected with -raw-field
+func raw(c config, path string) error {
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("path must start with /")
+	}
+	b, err := redfishGet(c, hostURL(c, path))
+	if err != nil {
+		return err
+	}
+	if c.rawfield != "" {
+		v, err := extractField(b, c.rawfield)
+		if err != nil {
+			return err
+		}
+		c.out.Result("%s\n", jsonString(v))
+		return nil
+	}
+	c.out.Result("%s\n", string(b))
+	return nil
+}
+
+// rawToFile streams response body of http GET request for specified redfish path to file specified with -raw-out
+// body is not buffered in memory, but it is still limited by -max-response-size
+func rawToFile(c config, path string) error {
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("path must start with /")
+	}
+	req, err := http.NewRequest("GET", hostURL(c, path), nil)
+	if err != nil {
+		return err
+	}
+	setHeaders(c, req)
+	resp, err := doRequest(c, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := readBody(c, resp)
+		printResponse(c, resp.StatusCode, body)
+		return newRedfishError("200 (OK)", resp.StatusCode, body)
+	}
+	f, err := os.Create(c.rawout)
+	if err != nil {
+		return fmt.Errorf("cannot create -raw-out file: %s", err)
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, c.maxbody+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > c.maxbody {
+		err = fmt.Errorf("response body exceeds -max-response-size of %d bytes", c.maxbody)
+	}
+	if err != nil {
+		os.Remove(c.rawout)
+		return err
+	}
+	if !c.quiet {
+		c.out.Info("host: %s %d bytes of %s written to %s\n", c.host, n, path, c.rawout)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+// type systemInfo describes member of systems collection
+type systemInfo struct {
+	OdataID string `json:"@odata.id"`
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name,omitempty"`
+}
+
+// listSystems prints members of systems collection without selecting one, so it works with any number of systems
+// members are not fetched, their names and ids are known only in expanded collection requested with -expand
+func listSystems(c config) error {
+	url := hostURL(c, "/redfish/v1/Systems")
+	if c.expand {
+		url += "?$expand=."
+	}
+	b, err := redfishGet(c, url)
+	if err != nil {
+		return err
+	}
+	systems, err := parseRedfishMembers(b)
+	if err != nil {
+		return err
+	}
+	if structured(c) {
+		res := result{Host: c.host, Systems: []systemInfo{}}
+		for _, m := range systems {
+			res.Systems = append(res.Systems, systemInfo{m.OdataID, m.ID, m.Name})
+		}
+		return printResult(c, res)
+	}
+	if !c.quiet {
+		c.out.Info("host: %s systems:\n", c.host)
+	}
+	for _, m := range systems {
+		c.out.Result("%s\n", m.label())
+	}
+	return nil
+}
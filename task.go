@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// taskReference returns url and id of task or job created by the action from response Location header or body
+// Dell BMCs reference the job only by its id (JID_...) in message arguments
+func taskReference(resp *http.Response, body []byte) (string, string) {
+	var rb struct {
+		OdataID      string `json:"@odata.id"`
+		OdataType    string `json:"@odata.type"`
+		ID           string `json:"Id"`
+		ExtendedInfo []struct {
+			MessageArgs []interface{} `json:"MessageArgs"`
+		} `json:"@Message.ExtendedInfo"`
+	}
+	json.Unmarshal(body, &rb)
+	url := resp.Header.Get("Location")
+	if isTask(rb.OdataID, rb.OdataType) {
+		if url == "" {
+			url = rb.OdataID
+		}
+		if rb.ID != "" {
+			return url, rb.ID
+		}
+	}
+	for _, info := range rb.ExtendedInfo {
+		for _, arg := range info.MessageArgs {
+			if s, ok := arg.(string); ok && strings.HasPrefix(s, "JID_") {
+				return url, s
+			}
+		}
+	}
+	if url != "" && isTask(url, "") {
+		return url, path.Base(strings.TrimRight(url, "/"))
+	}
+	return url, ""
+}
+
+// isTask returns true if resource url or type refers to redfish task or vendor job
+func isTask(url, odataType string) bool {
+	return strings.Contains(url, "/Tasks/") || strings.Contains(url, "/Jobs/") ||
+		strings.HasPrefix(odataType, "#Task.") || strings.Contains(odataType, "Job.")
+}
+
+// type task describes (partial) redfish task
+type task struct {
+	TaskState  string `json:"TaskState"`
+	TaskStatus string `json:"TaskStatus"`
+	Messages   []struct {
+		MessageID string `json:"MessageId"`
+		Message   string `json:"Message"`
+	} `json:"Messages"`
+}
+
+// followTask polls task created by the action until it finishes or -wait-timeout expires
+// messages of task which did not complete successfully are printed, as they usually explain the failure
+func followTask(c config, url string) error {
+	if !c.quiet {
+		c.out.Info("waiting for task %s ...\n", url)
+	}
+	// Location header may contain absolute url
+	taskURL := url
+	if strings.HasPrefix(url, "/") {
+		taskURL = hostURL(c, url)
+	}
+	deadline := time.Now().Add(c.waittime)
+	interval := c.pollint
+	for {
+		t, done, err := getTask(c, taskURL)
+		if err != nil {
+			return fmt.Errorf("cannot read task %s: %w", url, err)
+		}
+		if done {
+			if t.TaskState == "Completed" && (t.TaskStatus == "" || t.TaskStatus == "OK") {
+				tracef(c, "task %s completed", url)
+				return nil
+			}
+			for _, m := range t.Messages {
+				c.out.Error("task message: %s: %s\n", m.MessageID, m.Message)
+			}
+			return fmt.Errorf("task %s ended in state %s with status %s", url, t.TaskState, t.TaskStatus)
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("timeout waiting for task %s - task state: %s", url, t.TaskState)
+		}
+		if remaining > interval {
+			remaining = interval
+		}
+		if err := sleep(c, remaining); err != nil {
+			return err
+		}
+		if interval *= 2; interval > c.pollmax {
+			interval = c.pollmax
+		}
+	}
+}
+
+// getTask returns task read from task or task monitor url and whether it is finished
+// task monitor responds with 202 (Accepted) while the task is running and with result of the operation afterwards,
+// so response without task state is treated as successfully completed task
+func getTask(c config, url string) (task, bool, error) {
+	var t task
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return t, false, err
+	}
+	setHeaders(c, req)
+	resp, err := doRequest(c, req)
+	if err != nil {
+		return t, false, err
+	}
+	defer resp.Body.Close()
+	body, err := readBody(c, resp)
+	if err != nil {
+		return t, false, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNoContent {
+		printResponse(c, resp.StatusCode, body)
+		return t, false, newRedfishError("200 (OK), 202 (Accepted) or 204 (NoContent)", resp.StatusCode, body)
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &t); err != nil {
+			return t, false, fmt.Errorf("cannot parse task: %s", err)
+		}
+	}
+	switch t.TaskState {
+	case "":
+		if resp.StatusCode == http.StatusAccepted {
+			return t, false, nil
+		}
+		t.TaskState = "Completed"
+		return t, true, nil
+	case "Completed", "Exception", "Killed", "Cancelled":
+		return t, true, nil
+	}
+	return t, false, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+)
+
+// tlsVersions maps TLS protocol versions to their names
+var tlsVersions = map[uint16]string{
+	tls.VersionSSL30: "SSL 3.0",
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// tlsVersionName returns name of TLS protocol version
+func tlsVersionName(v uint16) string {
+	if name, ok := tlsVersions[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (0x%04x)", v)
+}
+
+// printTLSInfo prints negotiated TLS version, cipher suite and leaf certificate of the host with -tls-info
+// with -trace only the negotiated parameters are traced
+func printTLSInfo(c config, cs *tls.ConnectionState) {
+	version := tlsVersionName(cs.Version)
+	if cs.Version < tls.VersionTLS12 {
+		version += " (weak)"
+	}
+	cipher := tls.CipherSuiteName(cs.CipherSuite)
+	if !c.tlsinfo {
+		tracef(c, "tls: %s cipher: %s", version, cipher)
+		return
+	}
+	c.out.Result("host: %s tls version: %s cipher: %s\n", c.host, version, cipher)
+	if len(cs.PeerCertificates) == 0 {
+		return
+	}
+	cert := cs.PeerCertificates[0]
+	expiry := fmt.Sprintf("expires in %d days", int(time.Until(cert.NotAfter).Hours()/24))
+	if time.Now().After(cert.NotAfter) {
+		expiry = "EXPIRED"
+	}
+	c.out.Result("host: %s certificate subject: %s issuer: %s not after: %s (%s)\n", c.host, cert.Subject, cert.Issuer, cert.NotAfter.Format(time.RFC3339), expiry)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// tracef prints formatted discovery step if -trace is set
+func tracef(c config, format string, args ...interface{}) {
+	if c.trace {
+		c.out.Debug("%s", fmt.Sprintf("trace: "+format+"\n", args...))
+	}
+}
+
+// traceRequest returns request hook printing every request with its status code and duration
+func traceRequest(c config) requestHook {
+	return func(method, url string, status int, dur time.Duration, err error) {
+		if err != nil {
+			tracef(c, "%s %s failed after %s: %s", method, url, dur.Round(time.Millisecond), err)
+			return
+		}
+		tracef(c, "%s %s -> %d (%s) in %s", method, url, status, http.StatusText(status), dur.Round(time.Millisecond))
+	}
+}
+
+// traceServiceRoot fetches redfish service root and traces its URL and redfish version
+// errors are traced only, as service root is not required for discovery
+func traceServiceRoot(c config) {
+	url := hostURL(c, "/redfish/v1")
+	b, err := redfishGet(c, url)
+	if err != nil {
+		tracef(c, "service root: %s error: %s", url, err)
+		return
+	}
+	var root struct {
+		RedfishVersion string `json:"RedfishVersion"`
+	}
+	json.Unmarshal(b, &root)
+	tracef(c, "service root: %s redfish version: %s", url, root.RedfishVersion)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"time"
+)
+
+// exit code returned when waiting for action result is interrupted
+const exitWaitInterrupted = 4
+
+// errWaitTimeout is returned when expected power state is not reached within -wait-timeout
+var errWaitTimeout = errors.New("timeout waiting for power state")
+
+// maximum number of consecutive server errors tolerated while waiting, BMC answering with them is up but failing
+const maxServerErrors = 30
+
+// expectedState returns power state expected after successful action or empty string if it cannot be predicted
+func expectedState(act string) string {
+	switch act {
+	case "On", "ForceOn", "ForceRestart", "GracefulRestart", "PowerCycle", "Resume":
+		return "On"
+	case "ForceOff", "GracefulShutdown":
+		return "Off"
+	case "Pause":
+		return "Paused"
+	}
+	return ""
+}
+
+// verifyAction reads power state once after the action and prints warning if it does not match the action
+// restart actions are not verified, as power state during restart cannot be predicted
+func verifyAction(c config, sys system, act string) {
+	expected := expectedState(act)
+	if expected == "" || act == "ForceRestart" || act == "GracefulRestart" || act == "PowerCycle" {
+		tracef(c, "verify: resulting power state of %s action cannot be verified with single read", act)
+		return
+	}
+	state, err := pollPowerState(c, sys)
+	if err != nil {
+		c.out.Error("warning: cannot verify %s action: %s\n", act, err)
+		return
+	}
+	tracef(c, "verify: power state after %s action: %s", act, state)
+	if state == expected || state == "Powering"+expected {
+		return
+	}
+	c.out.Error("warning: host %s power state is %s after %s action, expected %s - action may be still in progress or may have been ignored (use -wait to wait for it)\n", c.host, state, act, expected)
+}
+
+// waitInterruptible waits for result of already submitted action like waitForAction, but stops waiting on interrupt signal
+// interrupted wait results in error with distinct exit code, as the action was already submitted and is not rolled back
+func waitInterruptible(c config, sys system, act string) error {
+	c, interrupted, stop := interruptible(c)
+	defer stop()
+	err := waitForAction(c, sys, act)
+	if interrupted() {
+		return &exitError{exitWaitInterrupted, fmt.Errorf("waiting interrupted - %s action was already submitted to host %s and was not rolled back", act, c.host)}
+	}
+	return err
+}
+
+// interruptible returns config with context cancelled on interrupt signal, function reporting if the signal was received
+// and function which must be called to restore default signal handling
+func interruptible(c config) (config, func() bool, func()) {
+	parent := c.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	var received int32
+	go func() {
+		select {
+		case <-sig:
+			atomic.StoreInt32(&received, 1)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	c.ctx = ctx
+	interrupted := func() bool {
+		return atomic.LoadInt32(&received) == 1
+	}
+	stop := func() {
+		signal.Stop(sig)
+		cancel()
+	}
+	return c, interrupted, stop
+}
+
+// waitForAction waits until the system reaches power state expected after specified action
+func waitForAction(c config, sys system, act string) error {
+	state := expectedState(act)
+	if state == "" {
+		if !c.quiet {
+			c.out.Info("resulting power state of %s action is unknown - not waiting\n", act)
+		}
+		return nil
+	}
+	err := waitForState(c, sys, state)
+	// operating system may ignore ACPI power button event, which BMC has no way to report
+	if act == "GracefulShutdown" && errors.Is(err, errWaitTimeout) {
+		c.out.Error("warning: %s\n", err)
+		return fmt.Errorf("GracefulShutdown request appears to have been ignored by the operating system - use ForceOff to power off immediately")
+	}
+	return err
+}
+
+// waitForState polls power state of the system until it reaches expected state or -wait-timeout expires
+// connection errors (like BMC web server restarting during reset) are treated as not ready yet,
+// server errors are tolerated up to maxServerErrors in a row, while client errors (like 401 or 404) stop waiting immediately
+func waitForState(c config, sys system, state string) error {
+	if !c.quiet {
+		c.out.Info("waiting for power state %s ...\n", state)
+	}
+	deadline := time.Now().Add(c.waittime)
+	// interval starts at -poll-interval and doubles up to -poll-max-interval,
+	// so quick transitions are caught early while long shutdowns do not flood the BMC with requests
+	interval := c.pollint
+	// last server error is reported separately, as it tells that BMC was reachable
+	var servererr error
+	servererrs := 0
+	for {
+		current, err := pollPowerState(c, sys)
+		var rerr *redfishError
+		switch {
+		case err == nil && current == state:
+			if !c.quiet {
+				c.out.Info("host: %s power state: %s\n", c.host, current)
+			}
+			return nil
+		case errors.As(err, &rerr) && rerr.statusCode < 500:
+			return err
+		case errors.As(err, &rerr):
+			servererr = err
+			if servererrs++; servererrs >= maxServerErrors {
+				return fmt.Errorf("BMC responded with server error %d times in a row while waiting for power state %s - last error: %s", servererrs, state, err)
+			}
+			tracef(c, "wait: server error %d of %d tolerated: %s", servererrs, maxServerErrors, err)
+		case err != nil:
+			servererrs = 0
+			tracef(c, "wait: BMC not reachable yet: %s", err)
+		default:
+			servererrs = 0
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			msg := fmt.Sprintf("current power state: %s", current)
+			if err != nil {
+				msg = fmt.Sprintf("last error: %s", err)
+			}
+			if servererr != nil && servererr != err {
+				msg += fmt.Sprintf(", last server error: %s", servererr)
+			}
+			return fmt.Errorf("%w %s - %s", errWaitTimeout, state, msg)
+		}
+		// jitter keeps hosts restarting together from being polled in lockstep
+		if d := jitter(interval); remaining > d {
+			remaining = d
+		}
+		if err := sleep(c, remaining); err != nil {
+			return err
+		}
+		if interval *= 2; interval > c.pollmax {
+			interval = c.pollmax
+		}
+	}
+}
+
+// pollPowerState returns current power state of already discovered system, always read from the host
+func pollPowerState(c config, sys system) (string, error) {
+	c.memo = nil
+	if sys.OdataID == "" {
+		return getPowerState(c)
+	}
+	b, err := redfishGet(c, hostURL(c, sys.OdataID))
+	if err != nil {
+		return "", err
+	}
+	var current system
+	if err := json.Unmarshal(b, &current); err != nil {
+		return "", err
+	}
+	return current.PowerState, nil
+}
+
+// waitForTCP tries to connect to -wait-for-tcp address until it succeeds or -wait-timeout expires
+// it tells that the operating system is up, which power state of the system cannot tell
+func waitForTCP(c config) error {
+	if !c.quiet {
+		c.out.Info("waiting for %s to accept connections ...\n", c.waittcp)
+	}
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	deadline := time.Now().Add(c.waittime)
+	interval := c.pollint
+	for {
+		d := net.Dialer{Timeout: time.Second * time.Duration(c.timeout)}
+		conn, err := d.DialContext(ctx, "tcp", c.waittcp)
+		if err == nil {
+			conn.Close()
+			if !c.quiet {
+				c.out.Info("host: %s %s accepts connections\n", c.host, c.waittcp)
+			}
+			return nil
+		}
+		tracef(c, "connection to %s failed: %s", c.waittcp, err)
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("timeout waiting for %s to accept connections - last error: %s", c.waittcp, err)
+		}
+		if remaining > interval {
+			remaining = interval
+		}
+		if err := sleep(c, remaining); err != nil {
+			return err
+		}
+		if interval *= 2; interval > c.pollmax {
+			interval = c.pollmax
+		}
+	}
+}
+
+// validHostPort returns true if address consists of host and port
+func validHostPort(addr string) bool {
+	host, port, err := net.SplitHostPort(addr)
+	return err == nil && host != "" && port != ""
+}
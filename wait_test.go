@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJitter(t *testing.T) {
+	for _, d := range []time.Duration{0, time.Nanosecond, time.Second, 5 * time.Second} {
+		for i := 0; i < 100; i++ {
+			if got := jitter(d); got < d-d/5 || got > d+d/5 {
+				t.Fatalf("jitter(%s) = %s, want within 20%%", d, got)
+			}
+		}
+	}
+}
+
+func TestWaitStopsOnRepeatedServerErrors(t *testing.T) {
+	// every read of the system after reset fails with server error
+	var reset int32
+	_, srv := newTestServer(t, "u", "p", func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodPost:
+				atomic.StoreInt32(&reset, 1)
+			case r.URL.Path == mockSystemURL && atomic.LoadInt32(&reset) == 1:
+				mockError(w, http.StatusInternalServerError, "Base.1.8.InternalError", "internal error")
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	_, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-quiet", "-action", "On", "-wait", "-poll-interval", "1ms", "-poll-max-interval", "1ms")
+	if err == nil || !strings.Contains(err.Error(), "server error 30 times in a row") {
+		t.Errorf("waiting with repeated server errors returned %v, want error after %d server errors", err, maxServerErrors)
+	}
+}
+
+func TestWaitTimeoutReportsLastServerError(t *testing.T) {
+	// server error is followed by connection failures, which are told apart in timeout error
+	var polls int32
+	_, srv := newTestServer(t, "u", "p", func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && r.URL.Path == mockSystemURL && atomic.AddInt32(&polls, 1) > 1 {
+				if atomic.LoadInt32(&polls) == 2 {
+					mockError(w, http.StatusServiceUnavailable, "Base.1.8.ServiceTemporarilyUnavailable", "restarting")
+					return
+				}
+				hj, _ := w.(http.Hijacker)
+				conn, _, _ := hj.Hijack()
+				conn.Close()
+				return
+			}
+			if r.Method == http.MethodPost {
+				// power state never changes
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	_, _, err := runTest(t, srv, "-user", "u", "-pass", "p", "-quiet", "-retries", "0", "-action", "On", "-wait", "-wait-timeout", "50ms", "-poll-interval", "5ms", "-poll-max-interval", "5ms")
+	if err == nil || !strings.Contains(err.Error(), "last server error") || !strings.Contains(err.Error(), "restarting") {
+		t.Errorf("wait timeout returned %v, want error with last server error", err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// type webhookResult describes result of actions on single host posted to -webhook
+type webhookResult struct {
+	result
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// notifyWebhook posts result of actions on the host to -webhook, status is failed if err is not nil
+func notifyWebhook(c config, res result, err error) {
+	wr := webhookResult{result: res, Status: "ok"}
+	if err != nil {
+		wr.Status, wr.Error = "failed", err.Error()
+	}
+	sendWebhook(c, wr)
+}
+
+// sendWebhook posts payload as json to -webhook
+// delivery failures are printed as warnings only, so they never change outcome of the run
+func sendWebhook(c config, payload interface{}) {
+	if err := postWebhook(c, payload); err != nil {
+		c.out.Error("warning: webhook delivery failed: %s\n", err)
+	}
+}
+
+// postWebhook posts payload as json to -webhook with Authorization header from -webhook-auth
+func postWebhook(c config, payload interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", c.webhook, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("invalid -webhook: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", c.agent)
+	if c.whauth != "" {
+		req.Header.Set("Authorization", c.whauth)
+	}
+	client := &http.Client{Timeout: time.Second * time.Duration(c.timeout)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status code: %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	tracef(c, "webhook: result posted to %s", c.webhook)
+	return nil
+}